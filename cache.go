@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/katallaxie/run/pkg/cache"
+	"github.com/katallaxie/run/pkg/config"
+	"github.com/katallaxie/run/pkg/spec"
+)
+
+// cacheStore returns the store of the outputs of the tasks.
+func cacheStore(cfg *config.Config, s *spec.Spec) (cache.Store, error) {
+	if s.Cache.URL != "" {
+		return cache.NewHTTP(s.Cache.URL, os.Getenv(s.Cache.TokenEnv)), nil
+	}
+
+	dir, err := cacheDir(cfg, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return cache.NewDir(dir), nil
+}
+
+// cacheDir returns the local directory of the cache. It defaults to
+// `run` in the cache directory of the user.
+func cacheDir(cfg *config.Config, s *spec.Spec) (string, error) {
+	if s.Cache.Dir == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+
+		return filepath.Join(dir, "run"), nil
+	}
+
+	if filepath.IsAbs(s.Cache.Dir) {
+		return s.Cache.Dir, nil
+	}
+
+	cwd := cfg.Flags.Dir
+	if cwd == "" {
+		var err error
+		if cwd, err = cfg.Cwd(); err != nil {
+			return "", err
+		}
+	}
+
+	return filepath.Join(cwd, s.Cache.Dir), nil
+}
+
+// pruneCache removes the entries of the local cache that have not been used
+// for the duration in the arguments, or all entries.
+func pruneCache(w io.Writer, cfg *config.Config, s *spec.Spec, args ...string) error {
+	if len(args) == 0 || args[0] != "prune" {
+		return fmt.Errorf("usage: run cache prune [duration]")
+	}
+
+	var olderThan time.Duration
+	if len(args) > 1 {
+		d, err := time.ParseDuration(args[1])
+		if err != nil {
+			return err
+		}
+		olderThan = d
+	}
+
+	dir, err := cacheDir(cfg, s)
+	if err != nil {
+		return err
+	}
+
+	n, err := cache.NewDir(dir).Prune(olderThan)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "removed %d entries from %s\n", n, dir)
+
+	return err
+}
@@ -2,10 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/katallaxie/run/pkg/plugin"
 	"github.com/katallaxie/run/pkg/proto"
+	"github.com/katallaxie/run/pkg/scaffold"
+	"github.com/katallaxie/run/pkg/tmpl"
 )
 
 func main() {
@@ -20,22 +27,17 @@ type server struct {
 	proto.UnimplementedPluginServer
 }
 
-// Start ...
+// Execute renders the scaffold at the `url` var to the `folder` var.
+// The diagnostic of a failure is returned in the response.
 func (s *server) Execute(ctx context.Context, req *proto.Execute_Request) (*proto.Execute_Response, error) {
-	resp := &proto.Execute_Response{}
-
-	p := NewGit()
-	_ = NewArchive()
-
-	err := p.CloneWithContext(ctx, req.Vars["url"], req.Vars["folder"])
-	if err != nil {
-		resp.Diagnostic = []*proto.Diagnostic{
-			proto.DiagnosticFromError(err),
-		}
-		return resp, err
+	if err := generate(ctx, req.GetVars()); err != nil {
+		return &proto.Execute_Response{
+			Status:     proto.Execute_FAILURE,
+			Diagnostic: []*proto.Diagnostic{diagnostic(err)},
+		}, nil
 	}
 
-	return &proto.Execute_Response{}, nil
+	return &proto.Execute_Response{Status: proto.Execute_SUCCESS}, nil
 }
 
 // Stop ...
@@ -44,3 +46,107 @@ func (s *server) Stop(ctx context.Context, req *proto.Stop_Request) (*proto.Stop
 
 	return &proto.Stop_Response{}, nil
 }
+
+// generate fetches the scaffold with the provider, the ref, the subdir, the strip-components
+// and the checksum of the vars, and renders it with the vars. The `ignore` var is a
+// comma-separated list of patterns.
+func generate(ctx context.Context, vars map[string]string) error {
+	url, folder := vars["url"], vars["folder"]
+	if url == "" || folder == "" {
+		return errors.New("the url and folder vars are required")
+	}
+
+	force := false
+	if v, ok := vars["force"]; ok {
+		var err error
+		if force, err = strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("force: %w", err)
+		}
+	}
+
+	strip := 0
+	if v, ok := vars["strip-components"]; ok {
+		var err error
+		if strip, err = strconv.Atoi(v); err != nil || strip < 0 {
+			return fmt.Errorf("strip-components: invalid value %q", v)
+		}
+	}
+
+	p, err := scaffold.NewProviderByName(vars["provider"], url,
+		scaffold.WithRef(vars["ref"]),
+		scaffold.WithSubdir(vars["subdir"]),
+		scaffold.WithStripComponents(strip),
+		scaffold.WithChecksum(vars["checksum"]),
+	)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempDir("", "gen-tmpl-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := p.CloneWithContext(ctx, url, tmp); err != nil {
+		return &fetchError{url: url, err: err}
+	}
+
+	m, err := scaffold.Load(tmp)
+	if err != nil {
+		return err
+	}
+
+	inputs, err := m.Fields(vars, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	fields := make(tmpl.Fields, len(vars))
+	for k, v := range vars {
+		fields[k] = v
+	}
+	for k, v := range inputs {
+		fields[k] = v
+	}
+
+	ignore := m.Ignore
+	for _, pattern := range strings.Split(vars["ignore"], ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			ignore = append(ignore, pattern)
+		}
+	}
+
+	return scaffold.Render(tmp, folder, fields, scaffold.WithForce(force), scaffold.WithIgnore(ignore...))
+}
+
+type fetchError struct {
+	url string
+	err error
+}
+
+func (e *fetchError) Error() string { return fmt.Sprintf("cannot fetch %s: %v", e.url, e.err) }
+func (e *fetchError) Unwrap() error { return e.err }
+
+// diagnostic returns the diagnostic of the error with the files it is about.
+func diagnostic(err error) *proto.Diagnostic {
+	d := &proto.Diagnostic{
+		Severity: proto.Diagnostic_ERROR,
+		Summary:  "cannot generate the scaffold",
+		Detail:   err.Error(),
+	}
+
+	var fetchErr *fetchError
+	if errors.As(err, &fetchErr) {
+		d.Summary = "cannot fetch the scaffold"
+	}
+
+	var fileErr *scaffold.FileError
+	if errors.As(err, &fileErr) {
+		d.Summary = "cannot render the scaffold"
+		d.Detail = fileErr.Err.Error()
+		d.FilePaths = []string{fileErr.Path}
+	}
+
+	return d
+}
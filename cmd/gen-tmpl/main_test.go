@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/katallaxie/run/pkg/proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Execute(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+
+	for name, content := range map[string]string{
+		"app/.sc.yaml":          "inputs: [{name: module, required: true}]\nignore: [LICENSE]",
+		"app/go.mod.tmpl":       "module {{.module}} // {{.owner}}\n",
+		"app/LICENSE":           "",
+		"app/{{.module}}.txt":   "{{.module}}",
+		"app/broken.yaml.tmpl":  "{{.missing}}",
+		"app/docs/index.md":     "",
+		"app/docs/internal.txt": "",
+	} {
+		path := filepath.Join(src, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	s := &server{}
+	vars := map[string]string{
+		"url":      src,
+		"folder":   dst,
+		"provider": "dir",
+		"subdir":   "app",
+		"module":   "app",
+		"owner":    "acme",
+		"ignore":   "broken.yaml.tmpl, docs/*.txt",
+	}
+
+	resp, err := s.Execute(context.Background(), &proto.Execute_Request{Vars: vars})
+	assert.NoError(t, err)
+	assert.Equal(t, proto.Execute_SUCCESS, resp.GetStatus())
+
+	b, err := os.ReadFile(filepath.Join(dst, "go.mod"))
+	assert.NoError(t, err)
+	assert.Equal(t, "module app // acme\n", string(b))
+	assert.FileExists(t, filepath.Join(dst, "app.txt"))
+	assert.FileExists(t, filepath.Join(dst, "docs/index.md"))
+	assert.NoFileExists(t, filepath.Join(dst, "LICENSE"))
+	assert.NoFileExists(t, filepath.Join(dst, "docs/internal.txt"))
+
+	resp, err = s.Execute(context.Background(), &proto.Execute_Request{Vars: vars})
+	assert.NoError(t, err)
+	assert.Equal(t, proto.Execute_FAILURE, resp.GetStatus())
+	require.Len(t, resp.GetDiagnostic(), 1)
+	assert.Equal(t, "cannot render the scaffold", resp.GetDiagnostic()[0].GetSummary())
+	assert.NotEmpty(t, resp.GetDiagnostic()[0].GetFilePaths())
+	assert.Error(t, proto.ErrorFromDiagnostics(resp.GetDiagnostic()))
+
+	vars["ignore"], vars["force"] = "", "true"
+	resp, _ = s.Execute(context.Background(), &proto.Execute_Request{Vars: vars})
+	assert.Equal(t, []string{"broken.yaml"}, resp.GetDiagnostic()[0].GetFilePaths())
+
+	vars["url"] = filepath.Join(src, "missing")
+	resp, _ = s.Execute(context.Background(), &proto.Execute_Request{Vars: vars})
+	assert.Equal(t, "cannot fetch the scaffold", resp.GetDiagnostic()[0].GetSummary())
+
+	resp, _ = s.Execute(context.Background(), &proto.Execute_Request{Vars: map[string]string{"url": src}})
+	assert.Equal(t, proto.Execute_FAILURE, resp.GetStatus())
+	assert.Nil(t, proto.ErrorFromDiagnostics(nil))
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/katallaxie/run/pkg/config"
+	"github.com/katallaxie/run/pkg/spec"
+
+	"github.com/spf13/pflag"
+)
+
+const (
+	// completeCmd is the hidden command that is called by the completion scripts
+	// with the words of the command line.
+	completeCmd = "__complete"
+	// completeFiles tells the completion scripts to complete file names.
+	completeFiles = ":files"
+)
+
+// completion writes the completion script for the shell, or the candidates
+// if called by the script.
+func completion(cfg *config.Config, flags *pflag.FlagSet, args ...string) error {
+	w := cfg.Stdout
+
+	if args[0] == completeCmd {
+		return complete(w, cfg, flags, args[1:])
+	}
+
+	if len(args) != 2 {
+		return fmt.Errorf("usage: run completion bash|zsh|fish")
+	}
+
+	switch args[1] {
+	case "bash":
+		_, err := io.WriteString(w, bashCompletion)
+		return err
+	case "zsh":
+		_, err := io.WriteString(w, zshCompletion)
+		return err
+	case "fish":
+		_, err := io.WriteString(w, fishCompletion)
+		return err
+	default:
+		return fmt.Errorf("unknown shell: %s", args[1])
+	}
+}
+
+// complete writes the candidates for the last of the words, one per line.
+// A candidate can have a description after a tab.
+func complete(w io.Writer, cfg *config.Config, flags *pflag.FlagSet, words []string) error {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur := words[len(words)-1]
+
+	var positional []string
+	var pending *pflag.Flag
+	values := make(map[string]string)
+	dash := false
+
+	for _, word := range words[:len(words)-1] {
+		switch {
+		case pending != nil:
+			values[pending.Name] = word
+			pending = nil
+		case dash:
+			positional = append(positional, word)
+		case word == "--":
+			dash = true
+		case strings.HasPrefix(word, "--"):
+			name, value, ok := strings.Cut(word[2:], "=")
+			f := flags.Lookup(name)
+			if f == nil {
+				continue
+			}
+
+			if ok {
+				values[f.Name] = value
+			} else if f.NoOptDefVal == "" {
+				pending = f
+			}
+		case strings.HasPrefix(word, "-") && len(word) > 1:
+			// the first shorthand that takes a value consumes the rest of the word
+			for i := 1; i < len(word); i++ {
+				f := flags.ShorthandLookup(word[i : i+1])
+				if f == nil || f.NoOptDefVal != "" {
+					continue
+				}
+
+				if i+1 < len(word) {
+					values[f.Name] = word[i+1:]
+				} else {
+					pending = f
+				}
+				break
+			}
+		default:
+			positional = append(positional, word)
+		}
+	}
+
+	var candidates []string
+	s := func() *spec.Spec {
+		s, err := nearestSpec(cfg, values["config"])
+		if err != nil {
+			return &spec.Spec{}
+		}
+		return s
+	}
+
+	switch {
+	case dash:
+		candidates = []string{completeFiles}
+	case pending != nil:
+		candidates = completeValue(pending.Name, cur, s)
+	case strings.HasPrefix(cur, "-"):
+		flags.VisitAll(func(f *pflag.Flag) {
+			if !f.Hidden {
+				candidates = append(candidates, "--"+f.Name+"\t"+f.Usage)
+			}
+		})
+	case len(positional) == 0:
+		sp := s()
+
+		names := []string{"completion"}
+		for name := range commands {
+			names = append(names, name)
+		}
+
+		// tasks take precedence over commands with the same name
+		for _, name := range names {
+			if !isTask(sp, name) {
+				candidates = append(candidates, name)
+			}
+		}
+		sort.Strings(candidates)
+		candidates = append(candidates, completeTasks(sp)...)
+	case positional[0] == "completion" && !isTask(s(), "completion"):
+		if len(positional) == 1 {
+			candidates = []string{"bash", "zsh", "fish"}
+		}
+	case positional[0] == "cache" && !isTask(s(), "cache"):
+		if len(positional) == 1 {
+			candidates = []string{"prune"}
+		}
+	default:
+		candidates = completeTasks(s())
+	}
+
+	for _, c := range candidates {
+		if c != completeFiles && !strings.HasPrefix(c, cur) {
+			continue
+		}
+
+		if _, err := fmt.Fprintln(w, c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// completeValue returns the candidates for the value of the flag.
+func completeValue(name, cur string, s func() *spec.Spec) []string {
+	switch name {
+	case "config", "dir", "report-junit", "report-json":
+		return []string{completeFiles}
+	case "plugin":
+		candidates := make([]string, 0)
+		for _, p := range s().Plugins {
+			candidates = append(candidates, describe(p.Id, p.Description))
+		}
+
+		return candidates
+	case "var":
+		if strings.Contains(cur, "=") {
+			return nil
+		}
+
+		sp := s()
+		keys := make(map[string]bool)
+		for k := range sp.Vars {
+			keys[k] = true
+		}
+		for _, t := range sp.Tasks {
+			for k := range t.Vars {
+				keys[k] = true
+			}
+		}
+
+		candidates := make([]string, 0, len(keys))
+		for k := range keys {
+			candidates = append(candidates, k+"=")
+		}
+		sort.Strings(candidates)
+
+		return candidates
+	case "format":
+		return []string{"text", "table", "json", "dot", "mermaid"}
+	case "output":
+		return []string{"interleaved", "group", "github"}
+	case "log-level":
+		return []string{"debug", "info", "warn", "error"}
+	case "log-format":
+		return []string{"text", "json"}
+	default:
+		return nil
+	}
+}
+
+// completeTasks returns the tasks that are not disabled.
+func completeTasks(s *spec.Spec) []string {
+	candidates := make([]string, 0, len(s.Tasks))
+	for _, name := range s.Names() {
+		t := s.Tasks[name]
+		if t.Disabled {
+			continue
+		}
+
+		candidates = append(candidates, describe(name, t.Desc))
+	}
+
+	return candidates
+}
+
+func describe(name, desc string) string {
+	desc = strings.Join(strings.Fields(desc), " ")
+	if desc == "" {
+		return name
+	}
+
+	return name + "\t" + desc
+}
+
+// nearestSpec loads the config file from the command line, or else the spec
+// in the working directory or the closest of its parents.
+func nearestSpec(cfg *config.Config, file string) (*spec.Spec, error) {
+	if file != "" {
+		return spec.Load(file)
+	}
+
+	dir, err := cfg.Cwd()
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(cfg.File)
+
+	for {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return spec.Load(path)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, fmt.Errorf("%s not found", name)
+		}
+		dir = parent
+	}
+}
+
+const bashCompletion = `# bash completion for run
+#
+# source <(run completion bash)
+
+_run_completion() {
+    local line="${COMP_LINE:0:COMP_POINT}"
+    local -a words
+    read -ra words <<< "$line"
+    [[ "$line" == *[[:space:]] ]] && words+=("")
+    local cur="${words[${#words[@]}-1]}"
+
+    local out
+    out=$("${words[0]}" __complete -- "${words[@]:1}" 2>/dev/null) || return 0
+
+    COMPREPLY=()
+    if [[ "$out" == ":files" ]]; then
+        compopt -o filenames 2>/dev/null
+        local IFS=$'\n'
+        COMPREPLY=($(compgen -f -- "$cur"))
+        return 0
+    fi
+
+    local c
+    while IFS= read -r c; do
+        [[ -n "$c" ]] && COMPREPLY+=("${c%%$'\t'*}")
+    done <<< "$out"
+
+    [[ "${COMPREPLY[0]}" == *= ]] && compopt -o nospace 2>/dev/null
+    return 0
+}
+
+complete -F _run_completion run
+`
+
+const zshCompletion = `#compdef run
+#
+# source <(run completion zsh)
+
+_run() {
+    local out
+    out=$(${words[1]} __complete -- "${(@)words[2,CURRENT]}" 2>/dev/null) || return 1
+
+    if [[ "$out" == ":files" ]]; then
+        _files
+        return
+    fi
+
+    local -a candidates nospace
+    local line name
+    for line in "${(@f)out}"; do
+        [[ -z "$line" ]] && continue
+        name="${line%%$'\t'*}"
+        name="${name//:/\\:}"
+        if [[ "$name" == *= ]]; then
+            nospace+=("$name")
+        elif [[ "$line" == *$'\t'* ]]; then
+            candidates+=("$name:${line#*$'\t'}")
+        else
+            candidates+=("$name")
+        fi
+    done
+
+    (( ${#candidates} )) && _describe 'run' candidates
+    (( ${#nospace} )) && _describe 'run' nospace -S ''
+    return 0
+}
+
+if [[ "$funcstack[1]" == "_run" ]]; then
+    _run "$@"
+else
+    compdef _run run
+fi
+`
+
+const fishCompletion = `# fish completion for run
+#
+# run completion fish | source
+
+function __run_complete
+    set -l tokens (commandline -opc)
+    set -l cur (commandline -ct)
+    set -q cur[1]; or set cur ""
+
+    set -l out ($tokens[1] __complete -- $tokens[2..-1] $cur 2>/dev/null)
+    if test "$out" = ":files"
+        __fish_complete_path $cur
+        return
+    end
+
+    set -q out[1]; and printf '%s\n' $out
+end
+
+complete -c run -f -a '(__run_complete)'
+`
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/katallaxie/run/pkg/config"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const completionSpec = `plugins:
+  - id: lint
+    path: ./bin/lint
+    description: Lints the code
+vars:
+  VERSION: v1
+tasks:
+  build:
+    desc: Builds the app
+    vars:
+      GOOS: linux
+  test: {}
+  hidden:
+    disabled: true
+`
+
+func TestComplete(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, ".run.yml")
+	require.NoError(t, os.WriteFile(file, []byte(completionSpec), 0o644))
+
+	cacheFile := filepath.Join(dir, "cache.yml")
+	require.NoError(t, os.WriteFile(cacheFile, []byte("tasks:\n  cache: {}\n  clean: {}\n  completion: {}\n"), 0o644))
+
+	commands := []string{"cache", "completion", "graph", "build\tBuilds the app", "test"}
+
+	tests := []struct {
+		name  string
+		words []string
+		want  []string
+	}{
+		{name: "commands and tasks", words: []string{""}, want: commands},
+		{name: "prefix", words: []string{"b"}, want: []string{"build\tBuilds the app"}},
+		{name: "flags", words: []string{"--log-"}, want: []string{"--log-format\tlog format (text, json)", "--log-level\tlog level (debug, info, warn, error), warn or debug with --verbose if not set"}},
+		{name: "flag value", words: []string{"--log-level", ""}, want: []string{"debug", "info", "warn", "error"}},
+		{name: "flag value prefix", words: []string{"--output", "g"}, want: []string{"group", "github"}},
+		{name: "inline flag value", words: []string{"--log-level=debug", ""}, want: commands},
+		{name: "bool flags", words: []string{"-v", "--dry", "t"}, want: []string{"test"}},
+		{name: "shorthand run", words: []string{"-dp", ""}, want: []string{"lint\tLints the code"}},
+		{name: "shorthand with value", words: []string{"-dplint", "t"}, want: []string{"test"}},
+		{name: "unknown flag", words: []string{"--unknown", "b"}, want: []string{"build\tBuilds the app"}},
+		{name: "vars", words: []string{"--var", ""}, want: []string{"GOOS=", "VERSION="}},
+		{name: "var value", words: []string{"--var", "GOOS="}, want: nil},
+		{name: "files", words: []string{"--report-json", ""}, want: []string{completeFiles}},
+		{name: "args", words: []string{"build", "--", ""}, want: []string{completeFiles}},
+		{name: "tasks after task", words: []string{"build", ""}, want: []string{"build\tBuilds the app", "test"}},
+		{name: "shells", words: []string{"completion", ""}, want: []string{"bash", "zsh", "fish"}},
+		{name: "cache", words: []string{"cache", ""}, want: []string{"prune"}},
+		{name: "task named cache", words: []string{"-c", cacheFile, "cache", ""}, want: []string{"cache", "clean", "completion"}},
+		{name: "task named completion", words: []string{"-c", cacheFile, "completion", ""}, want: []string{"cache", "clean", "completion"}},
+		{name: "commands named like tasks", words: []string{"-c", cacheFile, ""}, want: []string{"graph", "cache", "clean", "completion"}},
+		{name: "config", words: []string{"-c", ""}, want: []string{completeFiles}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.New()
+			cfg.File = file
+
+			fs := pflag.NewFlagSet("run", pflag.ContinueOnError)
+			defineFlags(fs, cfg)
+
+			words := tc.words
+			if tc.words[0] != "-c" {
+				words = append([]string{"--config", file}, words...)
+			}
+
+			var out bytes.Buffer
+			err := complete(&out, cfg, fs, words)
+			assert.NoError(t, err)
+
+			var got []string
+			if out.Len() > 0 {
+				got = strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/katallaxie/run/pkg/spec"
+)
+
+func graphTasks(w io.Writer, s *spec.Spec, format string, names ...string) error {
+	g, err := s.Graph(names...)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "", "dot":
+		return g.WriteDot(w)
+	case "mermaid":
+		return g.WriteMermaid(w)
+	default:
+		return fmt.Errorf("unknown graph format: %s", format)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/katallaxie/run/pkg/config"
+	"github.com/katallaxie/run/pkg/scaffold"
+	"github.com/katallaxie/run/pkg/utils"
+)
+
+// initSpec renders the scaffold at the source in the arguments to the directory,
+// if any, and writes a starter spec for the project if there is none.
+func initSpec(ctx context.Context, cfg *config.Config, dir, file string, args ...string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: run --init [source]")
+	}
+
+	if len(args) == 0 && exists(file) && !cfg.Flags.Force {
+		return fmt.Errorf("%s already exists, use --force to overwrite", file)
+	}
+
+	if len(args) == 1 {
+		if err := renderScaffold(ctx, cfg, dir, args[0]); err != nil {
+			return err
+		}
+
+		// the spec of the scaffold or the existing spec is kept
+		if exists(file) {
+			return nil
+		}
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	name := strings.ToLower(filepath.Base(abs))
+
+	return ioutil.WriteFile(file, scaffold.Starter(name, scaffold.Detect(dir)...), 0o644)
+}
+
+// renderScaffold fetches the scaffold at the source and renders it to the directory.
+// The inputs of the scaffold are taken from the --var flags, or else asked for
+// in the terminal.
+func renderScaffold(ctx context.Context, cfg *config.Config, dir, src string) error {
+	vars, err := utils.Map(cfg.Flags.Vars)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempDir("", "run-init-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	p := scaffold.NewProvider(src)
+	if err := p.CloneWithContext(ctx, src, tmp); err != nil {
+		return fmt.Errorf("cannot fetch scaffold %s: %w", src, err)
+	}
+
+	m, err := scaffold.Load(tmp)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader
+	if interactive(cfg.Stdin, cfg.Stderr) {
+		r = cfg.Stdin
+	}
+
+	fields, err := m.Fields(vars, r, cfg.Stderr)
+	if err != nil {
+		return err
+	}
+
+	return scaffold.Render(tmp, dir, fields, scaffold.WithForce(cfg.Flags.Force), scaffold.WithIgnore(m.Ignore...))
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/katallaxie/run/pkg/spec"
+)
+
+// listTask ...
+type listTask struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	DependsOn   []string  `json:"depends-on"`
+	Default     bool      `json:"default"`
+	Disabled    bool      `json:"disabled"`
+	Vars        spec.Vars `json:"vars"`
+	File        string    `json:"file"`
+}
+
+func listTasks(w io.Writer, s *spec.Spec, format string) error {
+	tasks := make([]listTask, 0, len(s.Tasks))
+	for _, name := range s.Names() {
+		t := s.Tasks[name]
+
+		deps := make([]string, len(t.DependsOn))
+		copy(deps, t.DependsOn)
+
+		vars := make(spec.Vars, len(t.Vars))
+		vars.Merge(t.Vars)
+
+		// the name of a task was its description before desc
+		desc := t.Desc
+		if desc == "" {
+			desc = t.Name
+		}
+
+		tasks = append(tasks, listTask{
+			Name:        name,
+			Description: desc,
+			DependsOn:   deps,
+			Default:     t.Default,
+			Disabled:    t.Disabled,
+			Vars:        vars,
+			File:        s.Path,
+		})
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(tasks)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tDESCRIPTION\tDEPENDS ON\tDEFAULT\tFILE")
+		for _, t := range tasks {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%s\n", t.Name, t.Description, strings.Join(t.DependsOn, ","), t.Default, t.File)
+		}
+
+		return tw.Flush()
+	case "", "text":
+		for _, t := range tasks {
+			if t.Description == "" {
+				fmt.Fprintln(w, t.Name)
+				continue
+			}
+			fmt.Fprintf(w, "%s (%s)\n", t.Name, t.Description)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown list format: %s", format)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/katallaxie/run/pkg/spec"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListTasks(t *testing.T) {
+	s := &spec.Spec{
+		Path: ".run.yml",
+		Tasks: spec.Tasks{
+			"build": spec.Task{Desc: "Builds the app", DependsOn: spec.DependsOn{"lint", "test"}, Vars: spec.Vars{"GOOS": "linux"}},
+			"test":  spec.Task{Name: "Runs the tests", Default: true},
+			"lint":  spec.Task{Disabled: true},
+		},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+		err    string
+	}{
+		{
+			format: "",
+			want:   "build (Builds the app)\nlint\ntest (Runs the tests)\n",
+		},
+		{
+			format: "table",
+			want: "NAME   DESCRIPTION     DEPENDS ON  DEFAULT  FILE\n" +
+				"build  Builds the app  lint,test   false    .run.yml\n" +
+				"lint                               false    .run.yml\n" +
+				"test   Runs the tests              true     .run.yml\n",
+		},
+		{
+			format: "json",
+			want: `[
+  {
+    "name": "build",
+    "description": "Builds the app",
+    "depends-on": [
+      "lint",
+      "test"
+    ],
+    "default": false,
+    "disabled": false,
+    "vars": {
+      "GOOS": "linux"
+    },
+    "file": ".run.yml"
+  },
+  {
+    "name": "lint",
+    "depends-on": [],
+    "default": false,
+    "disabled": true,
+    "vars": {},
+    "file": ".run.yml"
+  },
+  {
+    "name": "test",
+    "description": "Runs the tests",
+    "depends-on": [],
+    "default": true,
+    "disabled": false,
+    "vars": {},
+    "file": ".run.yml"
+  }
+]
+`,
+		},
+		{format: "yaml", err: "unknown list format: yaml"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			var out bytes.Buffer
+			err := listTasks(&out, s, tc.format)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, out.String())
+		})
+	}
+}
+
+func TestGraphTasks(t *testing.T) {
+	s := &spec.Spec{
+		Tasks: spec.Tasks{
+			"build": spec.Task{DependsOn: spec.DependsOn{"test"}},
+			"test":  spec.Task{},
+		},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+		err    string
+	}{
+		{format: "", want: "digraph run {\n  \"test\";\n  \"build\";\n  \"build\" -> \"test\";\n}\n"},
+		{format: "mermaid", want: "graph TD\n  t0[\"test\"]\n  t1[\"build\"]\n  t1 --> t0\n"},
+		{format: "json", err: "unknown graph format: json"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			var out bytes.Buffer
+			err := graphTasks(&out, s, tc.format, "build")
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, out.String())
+		})
+	}
+}
@@ -2,29 +2,40 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime/debug"
 	"time"
 
-	"github.com/andersnormal/pkg/utils/files"
 	"github.com/katallaxie/run/pkg/config"
+	"github.com/katallaxie/run/pkg/mask"
+	"github.com/katallaxie/run/pkg/output"
+	"github.com/katallaxie/run/pkg/picker"
 	"github.com/katallaxie/run/pkg/plugin"
+	"github.com/katallaxie/run/pkg/report"
 	"github.com/katallaxie/run/pkg/runner"
 	"github.com/katallaxie/run/pkg/spec"
+	"github.com/katallaxie/run/pkg/utils"
 	"mvdan.cc/sh/syntax"
 
 	"github.com/spf13/pflag"
-	"gopkg.in/yaml.v3"
+	"go.uber.org/zap"
+	"golang.org/x/term"
 )
 
 var (
 	version = ""
 )
 
-const usage = `Usage: run [-cflvsdpw] [--config] [--force] [--list] [--verbose] [--silent] [--dry] [--plugin] [--watch] [--validate] [--var] [--init] [--version] [--dir] [task...] 
+const usage = `Usage: run [-cflvsdpw] [--config] [--force] [--list] [--format] [--verbose] [--silent] [--dry] [--plugin] [--watch] [--validate] [--var] [--init [source]] [--version] [--dir] [--log-level] [--log-format] [--output] [--concurrency] [--grace-period] [--report-junit] [--report-json] [task...] 
+       run graph [--format] [task...]
+       run cache prune [duration]
+       run completion bash|zsh|fish
 
 '''
 spec: 	 1
@@ -42,8 +53,10 @@ func init() {
 }
 
 func main() {
+	masker := mask.New()
+
 	log.SetFlags(0)
-	log.SetOutput(os.Stderr)
+	log.SetOutput(masker.Writer(os.Stderr))
 
 	cfg := config.New()
 
@@ -57,28 +70,19 @@ func main() {
 		pflag.PrintDefaults()
 	}
 
-	pflag.BoolVarP(&cfg.Flags.Verbose, "verbose", "v", cfg.Flags.Verbose, "verbose output")
-	pflag.BoolVarP(&cfg.Flags.Help, "help", "h", cfg.Flags.Help, "show help")
-	pflag.BoolVar(&cfg.Flags.Init, "init", cfg.Flags.Init, "init config")
-	pflag.BoolVarP(&cfg.Flags.Force, "force", "f", cfg.Flags.Force, "force init")
-	pflag.BoolVarP(&cfg.Flags.Dry, "dry", "d", cfg.Flags.Dry, "dry run")
-	pflag.BoolVarP(&cfg.Flags.Silent, "silent", "s", cfg.Flags.Silent, "silent mode")
-	pflag.StringVarP(&cfg.File, "config", "c", cfg.File, "config file")
-	pflag.StringSliceVarP(&cfg.Flags.Env, "env", "e", cfg.Flags.Env, "environment variables")
-	pflag.StringVarP(&cfg.Flags.Plugin, "plugin", "p", cfg.Flags.Plugin, "plugin")
-	pflag.BoolVarP(&cfg.Flags.Validate, "validate", "V", cfg.Flags.Validate, "validate config")
-	pflag.BoolVarP(&cfg.Flags.List, "list", "l", cfg.Flags.List, "list tasks")
-	pflag.DurationVarP(&cfg.Flags.Timeout, "timeout", "t", time.Second*300, "timeout")
-	pflag.BoolVar(&cfg.Flags.Version, "version", cfg.Flags.Version, "version")
-	pflag.StringSliceVar(&cfg.Flags.Vars, "var", cfg.Flags.Vars, "variables")
-	pflag.BoolVarP(&cfg.Flags.Watch, "watch", "w", cfg.Flags.Watch, "watch")
-	pflag.StringVar(&cfg.Flags.Dir, "dir", "", "working directory")
+	defineFlags(pflag.CommandLine, cfg)
 	pflag.Parse()
 
-	cwd, err := cfg.Cwd()
+	logger, err := cfg.Logger(masker.Writer(cfg.Stderr))
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer func() { _ = logger.Sync() }()
+
+	cwd, err := cfg.Cwd()
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
 
 	if cfg.Flags.Dir != "" {
 		cwd = cfg.Flags.Dir
@@ -86,7 +90,16 @@ func main() {
 
 	if cfg.Flags.Verbose {
 		start := time.Now()
-		defer func() { log.Printf("time: %s", time.Since(start)) }()
+		defer func() { logger.Info("run finished", zap.Duration("duration", time.Since(start))) }()
+	}
+
+	// the completion runs without a spec in the working directory, but a task
+	// named completion takes precedence like for the other commands
+	if args := pflag.Args(); len(args) > 0 && (args[0] == completeCmd || args[0] == "completion" && !hasTask(cfg, "completion")) {
+		if err := completion(cfg, pflag.CommandLine, args...); err != nil {
+			logger.Fatal(err.Error())
+		}
+		os.Exit(0)
 	}
 
 	if cfg.Flags.Version {
@@ -99,91 +112,138 @@ func main() {
 		os.Exit(0)
 	}
 
+	if cfg.Flags.Init {
+		file := cfg.File
+		if cfg.Flags.Dir != "" && !pflag.CommandLine.Changed("config") {
+			file = filepath.Join(cwd, filepath.Base(cfg.File))
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, cfg.TermSignal)
+		defer stop()
+
+		if err := initSpec(ctx, cfg, cwd, file, pflag.Args()...); err != nil {
+			logger.Fatal(err.Error())
+		}
+		os.Exit(0)
+	}
+
 	s, err := cfg.LoadSpec()
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal(err.Error())
 	}
 
 	if cfg.Flags.Validate {
 		err = s.Validate()
 		if err != nil {
-			log.Fatal(err)
+			logger.Fatal(err.Error())
 		}
 		os.Exit(0)
 	}
 
 	if cfg.Flags.List {
-		for k, t := range s.Tasks {
-			log.Printf("%s (%s)", k, t.Name)
+		if err := listTasks(cfg.Stdout, s, cfg.Flags.Format); err != nil {
+			logger.Fatal(err.Error())
 		}
 		os.Exit(0)
 	}
 
-	if cfg.Flags.Init {
-		s := &spec.Spec{
-			Spec:    1,
-			Version: "0.0.1",
-			Tasks:   map[string]spec.Task{},
-		}
-
-		b, err := yaml.Marshal(&s)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		ok, err := files.FileExists(cfg.File)
-		if err != nil {
-			log.Fatal(err)
-		}
+	args, cliArgs, err := parseArgs()
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
 
-		if ok && !cfg.Flags.Force {
-			log.Fatalf("%s already exists, use --force to overwrite", cfg.File)
+	// tasks take precedence over commands with the same name
+	if len(args) > 0 && !isTask(s, args[0]) {
+		if cmd, ok := commands[args[0]]; ok {
+			if err := cmd(cfg, s, args[1:]); err != nil {
+				logger.Fatal(err.Error())
+			}
+			os.Exit(0)
 		}
+	}
 
-		f, err := os.Create(cfg.File)
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer f.Close()
+	vars, err := utils.Map(cfg.Flags.Vars)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
 
-		_, err = f.Write(b)
-		if err != nil {
-			log.Fatal(err)
-		}
+	env, err := utils.Map(cfg.Flags.Env)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
 
-		os.Exit(0)
+	mode := s.Output
+	if cfg.Flags.Output != "" {
+		mode = cfg.Flags.Output
 	}
 
-	args, cliArgs, err := parseArgs()
+	color := term.IsTerminal(int(cfg.Stdout.Fd())) && os.Getenv("NO_COLOR") == ""
+
+	out, err := output.New(mode, output.WithColor(color))
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal(err.Error())
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	r := runner.WithContext(ctx, runner.WithSpec(s), runner.WithWorkingDir(cwd))
+	received, kill := trap(cancel, cfg, logger)
+
+	rep := report.New()
+
+	store, err := cacheStore(cfg, s)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	r := runner.WithContext(
+		ctx,
+		runner.WithSpec(s),
+		runner.WithWorkingDir(cwd),
+		runner.WithVars(vars),
+		runner.WithEnv(env),
+		runner.WithArgs(cliArgs),
+		runner.WithReport(rep),
+		runner.WithCache(store),
+		runner.WithMasker(masker),
+		runner.WithDry(cfg.Flags.Dry),
+		runner.WithSilent(cfg.Flags.Silent),
+		runner.WithLogger(logger),
+		runner.WithOutput(out),
+		runner.WithConcurrency(cfg.Flags.Concurrency),
+		runner.WithTermSignal(cfg.TermSignal),
+		runner.WithKillSignal(cfg.KillSignal),
+		runner.WithGracePeriod(cfg.GracePeriod),
+		runner.WithKill(kill),
+	)
 
 	r.Lock()
 	defer r.Unlock()
 
 	if cfg.Flags.Plugin != "" {
-		m := &plugin.Meta{Path: cfg.Flags.Plugin}
+		m := &plugin.Meta{
+			Path:   pluginPath(s, cfg.Flags.Plugin),
+			Logger: logger,
+			Stdout: r.Stdout(),
+			Stderr: r.Stderr(),
+		}
 		f := m.Factory(ctx)
 
 		p, err := f()
 		if err != nil {
-			log.Fatal(err)
+			logger.Fatal(err.Error())
 		}
 		defer p.Close()
 
-		pp := s.Vars
+		pp := make(spec.Vars)
+		pp.Merge(s.Vars)
+		pp.Merge(vars)
 
 		if _, err := p.Execute(plugin.ExecuteRequest{
 			Vars:      pp,
-			Arguments: cliArgs,
+			Arguments: quotePatterns(cliArgs),
 		}); err != nil {
-			log.Fatal(err)
+			logger.Fatal(err.Error())
 		}
 
 		os.Exit(0)
@@ -191,24 +251,104 @@ func main() {
 
 	tasks, err := s.Find(args...)
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal(err.Error())
 	}
 
 	defaultTasks := s.Default()
 
 	if len(tasks) == 0 && len(defaultTasks) == 0 {
-		log.Fatal("no default task")
+		if !interactive(cfg.Stdin, cfg.Stderr) {
+			logger.Fatal("no default task")
+		}
+
+		picked, err := pickTasks(cfg.Stdin, cfg.Stderr, s)
+		if errors.Is(err, picker.ErrCanceled) {
+			os.Exit(exitCode(os.Interrupt))
+		}
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+
+		tasks, err = s.Find(picked...)
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
 	}
 
 	if len(tasks) == 0 {
 		tasks = defaultTasks
 	}
 
-	if err := r.RunTasks(tasks...); err != nil {
-		log.Fatal(err)
+	err = r.RunTasks(tasks...)
+
+	if rerr := writeReports(cfg, rep, masker); rerr != nil {
+		logger.Error(rerr.Error())
+	}
+
+	if sig := received(); sig != nil {
+		if err != nil {
+			logger.Error(err.Error())
+		}
+		os.Exit(exitCode(sig))
+	}
+
+	if err != nil {
+		logger.Fatal(err.Error())
 	}
 }
 
+// defineFlags defines the flags of run with the config as destination.
+func defineFlags(fs *pflag.FlagSet, cfg *config.Config) {
+	fs.BoolVarP(&cfg.Flags.Verbose, "verbose", "v", cfg.Flags.Verbose, "verbose output")
+	fs.BoolVarP(&cfg.Flags.Help, "help", "h", cfg.Flags.Help, "show help")
+	fs.BoolVar(&cfg.Flags.Init, "init", cfg.Flags.Init, "init config")
+	fs.BoolVarP(&cfg.Flags.Force, "force", "f", cfg.Flags.Force, "force init")
+	fs.BoolVarP(&cfg.Flags.Dry, "dry", "d", cfg.Flags.Dry, "dry run")
+	fs.BoolVarP(&cfg.Flags.Silent, "silent", "s", cfg.Flags.Silent, "silent mode")
+	fs.StringVarP(&cfg.File, "config", "c", cfg.File, "config file")
+	fs.StringSliceVarP(&cfg.Flags.Env, "env", "e", cfg.Flags.Env, "environment variables")
+	fs.StringVarP(&cfg.Flags.Plugin, "plugin", "p", cfg.Flags.Plugin, "plugin")
+	fs.BoolVarP(&cfg.Flags.Validate, "validate", "V", cfg.Flags.Validate, "validate config")
+	fs.BoolVarP(&cfg.Flags.List, "list", "l", cfg.Flags.List, "list tasks")
+	fs.StringVar(&cfg.Flags.Format, "format", cfg.Flags.Format, "output format (list: text, table, json; graph: dot, mermaid)")
+	fs.DurationVarP(&cfg.Flags.Timeout, "timeout", "t", time.Second*300, "timeout")
+	fs.BoolVar(&cfg.Flags.Version, "version", cfg.Flags.Version, "version")
+	fs.StringSliceVar(&cfg.Flags.Vars, "var", cfg.Flags.Vars, "variables")
+	fs.BoolVarP(&cfg.Flags.Watch, "watch", "w", cfg.Flags.Watch, "watch")
+	fs.StringVar(&cfg.Flags.Dir, "dir", "", "working directory")
+	fs.StringVar(&cfg.Flags.Output, "output", cfg.Flags.Output, "output mode (interleaved, group, github)")
+	fs.IntVarP(&cfg.Flags.Concurrency, "concurrency", "j", 1, "number of tasks to run at the same time")
+	fs.StringVar(&cfg.Flags.ReportJUnit, "report-junit", cfg.Flags.ReportJUnit, "write a report in the JUnit XML format to the file")
+	fs.StringVar(&cfg.Flags.ReportJSON, "report-json", cfg.Flags.ReportJSON, "write a report in the JSON format to the file")
+	fs.DurationVar(&cfg.GracePeriod, "grace-period", cfg.GracePeriod, "time for steps to exit after the term signal")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "log level (debug, info, warn, error), warn or debug with --verbose if not set")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "log format (text, json)")
+}
+
+// command ...
+type command func(cfg *config.Config, s *spec.Spec, args []string) error
+
+var commands = map[string]command{
+	"graph": func(cfg *config.Config, s *spec.Spec, args []string) error {
+		return graphTasks(cfg.Stdout, s, cfg.Flags.Format, args...)
+	},
+	"cache": func(cfg *config.Config, s *spec.Spec, args []string) error {
+		return pruneCache(cfg.Stdout, cfg, s, args...)
+	},
+}
+
+// isTask reports if the spec has a task with the name.
+func isTask(s *spec.Spec, name string) bool {
+	_, ok := s.Tasks[name]
+	return ok
+}
+
+// hasTask reports if the spec of the config can be loaded and has the task.
+func hasTask(cfg *config.Config, name string) bool {
+	s, err := cfg.LoadSpec()
+	return err == nil && isTask(s, name)
+}
+
 func parseArgs() ([]string, []string, error) {
 	args := pflag.Args()
 	dashPos := pflag.CommandLine.ArgsLenAtDash()
@@ -217,13 +357,28 @@ func parseArgs() ([]string, []string, error) {
 		return args, []string{}, nil
 	}
 
-	cliArgs := make([]string, 0)
-	for _, arg := range args[dashPos:] {
-		arg = syntax.QuotePattern(arg)
-		cliArgs = append(cliArgs, arg)
+	return args[:dashPos], args[dashPos:], nil
+}
+
+func quotePatterns(args []string) []string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, syntax.QuotePattern(arg))
+	}
+
+	return quoted
+}
+
+// pluginPath returns the path of the plugin with the id in the spec,
+// or else the argument as path.
+func pluginPath(s *spec.Spec, plugin string) string {
+	for _, p := range s.Plugins {
+		if p.Id == plugin && p.Path != "" {
+			return p.Path
+		}
 	}
 
-	return args[:dashPos], cliArgs, nil
+	return plugin
 }
 
 func getVersion() string {
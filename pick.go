@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+
+	"github.com/katallaxie/run/pkg/picker"
+	"github.com/katallaxie/run/pkg/spec"
+
+	"golang.org/x/term"
+)
+
+// interactive reports if the tasks can be picked in the terminal.
+func interactive(stdin, stderr *os.File) bool {
+	return term.IsTerminal(int(stdin.Fd())) && term.IsTerminal(int(stderr.Fd()))
+}
+
+// pickTasks opens a fuzzy search over the tasks that are not disabled.
+// The picker is drawn to stderr to keep stdout to the tasks.
+func pickTasks(stdin, stderr *os.File, s *spec.Spec) ([]string, error) {
+	items := make([]picker.Item, 0, len(s.Tasks))
+	for _, name := range s.Names() {
+		t := s.Tasks[name]
+		if t.Disabled {
+			continue
+		}
+
+		items = append(items, picker.Item{Name: name, Desc: t.Desc, Deps: t.DependsOn})
+	}
+
+	state, err := term.MakeRaw(int(stdin.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = term.Restore(int(stdin.Fd()), state) }()
+
+	p := picker.New(items,
+		picker.WithPrompt("run> "),
+		picker.WithColor(os.Getenv("NO_COLOR") == ""),
+	)
+
+	return p.Run(stdin, stderr)
+}
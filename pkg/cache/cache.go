@@ -0,0 +1,18 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned if there is no entry for a key.
+var ErrNotFound = errors.New("cache entry not found")
+
+// Store stores the outputs of tasks by the hash of their inputs.
+type Store interface {
+	// Get returns the entry of the key, or ErrNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Put stores the entry of the key.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+}
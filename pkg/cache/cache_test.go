@@ -0,0 +1,145 @@
+package cache_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/katallaxie/run/pkg/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, dir string, files ...string) {
+	for _, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(f), 0644))
+	}
+}
+
+func TestGlob(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.go", "pkg/b.go", "pkg/sub/c.go", "pkg/sub/c.txt", "docs/d.go")
+
+	type test struct {
+		patterns []string
+		expected []string
+	}
+
+	tests := []test{
+		{patterns: []string{"*.go"}, expected: []string{"a.go"}},
+		{patterns: []string{"**/*.go"}, expected: []string{"a.go", "docs/d.go", "pkg/b.go", "pkg/sub/c.go"}},
+		{patterns: []string{"pkg/**"}, expected: []string{"pkg/b.go", "pkg/sub/c.go", "pkg/sub/c.txt"}},
+		{patterns: []string{"pkg/sub/c.txt", "a.go"}, expected: []string{"a.go", "pkg/sub/c.txt"}},
+		{patterns: []string{"missing/**"}, expected: []string{}},
+	}
+
+	for _, tc := range tests {
+		files, err := cache.Glob(dir, tc.patterns)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, files)
+	}
+}
+
+func TestPack_Unpack(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	writeFiles(t, src, "out/a.txt", "out/sub/b.txt")
+
+	var buf bytes.Buffer
+	err := cache.Pack(&buf, src, []string{"out/a.txt", "out/sub/b.txt"})
+	assert.NoError(t, err)
+
+	files, err := cache.Unpack(&buf, dst)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"out/a.txt", "out/sub/b.txt"}, files)
+
+	b, err := os.ReadFile(filepath.Join(dst, "out", "sub", "b.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "out/sub/b.txt", string(b))
+
+	buf.Reset()
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "../evil", Mode: 0644, Typeflag: tar.TypeReg}))
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gw.Close())
+
+	_, err = cache.Unpack(&buf, dst)
+	assert.Error(t, err)
+}
+
+func testStore(t *testing.T, s cache.Store) {
+	ctx := context.Background()
+
+	_, err := s.Get(ctx, "abcdef")
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+
+	err = s.Put(ctx, "abcdef", strings.NewReader("entry"), 5)
+	assert.NoError(t, err)
+
+	rc, err := s.Get(ctx, "abcdef")
+	assert.NoError(t, err)
+	defer rc.Close()
+
+	b, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "entry", string(b))
+}
+
+func TestDir(t *testing.T) {
+	d := cache.NewDir(t.TempDir())
+	testStore(t, d)
+
+	n, err := d.Prune(0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	_, err = d.Get(context.Background(), "abcdef")
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+}
+
+func TestHTTP(t *testing.T) {
+	var mu sync.Mutex
+	entries := make(map[string][]byte)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			b, ok := entries[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(b)
+		case http.MethodPut:
+			b, _ := io.ReadAll(r.Body)
+			entries[r.URL.Path] = b
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer srv.Close()
+
+	testStore(t, cache.NewHTTP(srv.URL+"/cache/", "token"))
+
+	_, err := cache.NewHTTP(srv.URL, "").Get(context.Background(), "abcdef")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, cache.ErrNotFound)
+}
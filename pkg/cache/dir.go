@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var _ Store = (*Dir)(nil)
+
+// Dir stores the entries as files in a local directory.
+type Dir struct {
+	Path string
+}
+
+// NewDir ...
+func NewDir(path string) *Dir {
+	return &Dir{Path: path}
+}
+
+// Get ...
+func (d *Dir) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	path := d.path(key)
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the modification time is the last use of the entry for Prune
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
+	return f, nil
+}
+
+// Put ...
+func (d *Dir) Put(_ context.Context, key string, r io.Reader, _ int64) error {
+	path := d.path(key)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// entries are written to a temporary file first, so that readers never see partial entries
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// Prune removes the entries that have not been used for the duration.
+// It returns the number of removed entries.
+func (d *Dir) Prune(olderThan time.Duration) (int, error) {
+	deadline := time.Now().Add(-olderThan)
+	removed := 0
+
+	err := filepath.WalkDir(d.Path, func(path string, e fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		if err != nil || e.IsDir() {
+			return err
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+
+		if info.ModTime().After(deadline) {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+
+		return nil
+	})
+
+	return removed, err
+}
+
+func (d *Dir) path(key string) string {
+	return filepath.Join(d.Path, key[:2], key+".tar.gz")
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Glob returns the sorted paths of the files in dir that match the patterns.
+// The paths are relative to dir and use forward slashes. In addition to the
+// syntax of path.Match, `**` matches any number of directories.
+func Glob(dir string, patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		pattern = path.Clean(filepath.ToSlash(pattern))
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+
+		segments := strings.Split(pattern, "/")
+
+		// only the directory of the literal prefix of the pattern is walked
+		base := 0
+		for base < len(segments)-1 && !hasMeta(segments[base]) {
+			base++
+		}
+		root := filepath.Join(dir, filepath.FromSlash(strings.Join(segments[:base], "/")))
+
+		err := filepath.WalkDir(root, func(p string, e fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+
+			if err != nil || e.IsDir() {
+				return err
+			}
+
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+
+			if match(segments, strings.Split(rel, "/")) {
+				seen[rel] = true
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files := make([]string, 0, len(seen))
+	for f := range seen {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func match(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if match(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, `*?[\`)
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var _ Store = (*HTTP)(nil)
+
+// HTTP stores the entries on a server at <url>/<key>.
+//
+// An entry is read with GET, which returns 404 if there is no entry,
+// and is written with PUT. The token is sent as bearer token if set.
+type HTTP struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+// NewHTTP ...
+func NewHTTP(url, token string) *HTTP {
+	return &HTTP{URL: strings.TrimSuffix(url, "/"), Token: token, Client: http.DefaultClient}
+}
+
+// Get ...
+func (h *HTTP) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := h.do(ctx, http.MethodGet, key, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		res.Body.Close()
+		return nil, ErrNotFound
+	case res.StatusCode != http.StatusOK:
+		res.Body.Close()
+		return nil, fmt.Errorf("cache: GET %s: %s", key, res.Status)
+	}
+
+	return res.Body, nil
+}
+
+// Put ...
+func (h *HTTP) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	res, err := h.do(ctx, http.MethodPut, key, r, size)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("cache: PUT %s: %s", key, res.Status)
+	}
+
+	return nil
+}
+
+func (h *HTTP) do(ctx context.Context, method, key string, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, h.URL+"/"+key, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.ContentLength = size
+		req.Header.Set("Content-Type", "application/gzip")
+	}
+
+	if h.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.Token)
+	}
+
+	return h.Client.Do(req)
+}
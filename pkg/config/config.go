@@ -1,38 +1,49 @@
 package config
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/katallaxie/run/pkg/spec"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // Flags ...
 type Flags struct {
-	Dry      bool
-	Env      []string
-	Dir      string
-	Force    bool
-	Help     bool
-	Init     bool
-	List     bool
-	Plugin   string
-	Silent   bool
-	Timeout  time.Duration
-	Validate bool
-	Vars     []string
-	Verbose  bool
-	Version  bool
-	Watch    bool
+	Concurrency int
+	Dry         bool
+	Env         []string
+	Dir         string
+	Force       bool
+	Format      string
+	Help        bool
+	Init        bool
+	List        bool
+	Output      string
+	Plugin      string
+	ReportJSON  string
+	ReportJUnit string
+	Silent      bool
+	Timeout     time.Duration
+	Validate    bool
+	Vars        []string
+	Verbose     bool
+	Version     bool
+	Watch       bool
 }
 
 // Config ...
 type Config struct {
 	// Verbose toggles the verbosity
 	Verbose bool
-	// LogLevel is the level with with to log for this config
+	// LogLevel is the level with with to log for this config.
+	// If it is empty, DefaultLogLevel or debug with Flags.Verbose is used.
 	LogLevel string `mapstructure:"log_level"`
 	// LogFormat is the format that is used for logging
 	LogFormat string `mapstructure:"log_format"`
@@ -42,6 +53,8 @@ type Config struct {
 	TermSignal syscall.Signal
 	// KillSignal ...
 	KillSignal syscall.Signal
+	// GracePeriod is the time the steps have to exit after the TermSignal before the KillSignal is sent.
+	GracePeriod time.Duration
 	// File...
 	File string
 	// FileMode ...
@@ -56,13 +69,16 @@ type Config struct {
 	Stderr *os.File
 }
 
+// DefaultLogLevel is the level of the logs if no level is set.
+const DefaultLogLevel = "warn"
+
 // New ...
 func New() *Config {
 	return &Config{
 		File:         ".run.yml",
-		KillSignal:   syscall.SIGINT,
+		KillSignal:   syscall.SIGKILL,
+		GracePeriod:  10 * time.Second,
 		LogFormat:    "text",
-		LogLevel:     "warn",
 		ReloadSignal: syscall.SIGHUP,
 		TermSignal:   syscall.SIGTERM,
 		Stdin:        os.Stdin,
@@ -96,3 +112,38 @@ func (c *Config) LoadSpec() (*spec.Spec, error) {
 
 	return s, nil
 }
+
+// Logger returns a logger with the configured level and format.
+func (c *Config) Logger(w io.Writer) (*zap.Logger, error) {
+	l := c.LogLevel
+	if l == "" && c.Flags.Verbose {
+		l = "debug"
+	}
+
+	if l == "" {
+		l = DefaultLogLevel
+	}
+
+	level, err := zapcore.ParseLevel(l)
+	if err != nil {
+		return nil, err
+	}
+
+	encCfg := zap.NewProductionEncoderConfig()
+	encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	encCfg.EncodeDuration = zapcore.StringDurationEncoder
+
+	var enc zapcore.Encoder
+	switch c.LogFormat {
+	case "json":
+		enc = zapcore.NewJSONEncoder(encCfg)
+	case "text":
+		encCfg.TimeKey = ""
+		encCfg.EncodeLevel = zapcore.CapitalLevelEncoder
+		enc = zapcore.NewConsoleEncoder(encCfg)
+	default:
+		return nil, fmt.Errorf("unknown log format: %s", c.LogFormat)
+	}
+
+	return zap.New(zapcore.NewCore(enc, zapcore.AddSync(w), level)), nil
+}
@@ -0,0 +1,74 @@
+package config_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/katallaxie/run/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestConfig_Logger(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   string
+		format  string
+		verbose bool
+		want    []string
+		err     bool
+	}{
+		{name: "default", format: "text", want: []string{"WARN\twarn", "ERROR\terror"}},
+		{name: "verbose", format: "text", verbose: true, want: []string{"DEBUG\tdebug", "INFO\tinfo", "WARN\twarn", "ERROR\terror"}},
+		{name: "level over verbose", level: "error", format: "text", verbose: true, want: []string{"ERROR\terror"}},
+		{name: "info", level: "info", format: "text", want: []string{"INFO\tinfo", "WARN\twarn", "ERROR\terror"}},
+		{name: "json", level: "debug", format: "json", want: []string{"debug", "info", "warn", "error"}},
+		{name: "unknown level", level: "loud", format: "text", err: true},
+		{name: "unknown format", format: "xml", err: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.New()
+			cfg.LogLevel = tc.level
+			cfg.LogFormat = tc.format
+			cfg.Flags.Verbose = tc.verbose
+
+			var out bytes.Buffer
+			logger, err := cfg.Logger(&out)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.Warn("warn")
+			logger.Error("error", zap.String("task", "build"))
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			require.Len(t, lines, len(tc.want))
+
+			for i, line := range lines {
+				if tc.format == "json" {
+					var record map[string]interface{}
+					require.NoError(t, json.Unmarshal([]byte(line), &record))
+					assert.Equal(t, tc.want[i], record["level"])
+					assert.Equal(t, tc.want[i], record["msg"])
+					continue
+				}
+
+				assert.True(t, strings.HasPrefix(line, tc.want[i]), line)
+			}
+
+			if tc.format == "text" {
+				assert.Contains(t, lines[len(lines)-1], `{"task": "build"}`)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package dotenv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// LookupFunc ...
+type LookupFunc func(key string) (string, bool)
+
+// Read reads the variables from a file in the .env format.
+func Read(file string, lookup LookupFunc) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	env, err := Parse(f, lookup)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return env, nil
+}
+
+// Parse parses variables in the .env format.
+//
+// Lines may be prefixed with `export`. Values can be unquoted, single quoted
+// or double quoted. Unquoted and double quoted values expand `$VAR` and `${VAR}`
+// from the variables that are defined before, then from lookup.
+func Parse(r io.Reader, lookup LookupFunc) (map[string]string, error) {
+	env := make(map[string]string)
+
+	expand := func(s string) string {
+		return os.Expand(s, func(key string) string {
+			if v, ok := env[key]; ok {
+				return v
+			}
+
+			if lookup != nil {
+				v, _ := lookup(key)
+				return v
+			}
+
+			return ""
+		})
+	}
+
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		idx := strings.Index(line, "=")
+		if idx < 1 {
+			return nil, fmt.Errorf("line %d: invalid variable: %s", i+1, line)
+		}
+
+		key := strings.TrimSpace(line[:idx])
+		if strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: invalid variable name: %s", i+1, key)
+		}
+
+		value := strings.TrimSpace(line[idx+1:])
+
+		if len(value) == 0 || (value[0] != '"' && value[0] != '\'') {
+			if idx := strings.Index(value, " #"); idx >= 0 {
+				value = strings.TrimSpace(value[:idx])
+			}
+			env[key] = expand(value)
+
+			continue
+		}
+
+		quote := value[0]
+		value = value[1:]
+		start := i
+
+		// quoted values may span multiple lines
+		for {
+			if end := closingQuote(value, quote); end >= 0 {
+				value = value[:end]
+				break
+			}
+
+			i++
+			if i >= len(lines) {
+				return nil, fmt.Errorf("line %d: unterminated quoted value for %s", start+1, key)
+			}
+			value += "\n" + lines[i]
+		}
+
+		if quote == '\'' {
+			env[key] = value
+			continue
+		}
+
+		// escaped dollar signs are protected from the expansion
+		env[key] = strings.ReplaceAll(expand(unescape(value)), "\x00", "$")
+	}
+
+	return env, nil
+}
+
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && quote == '"' {
+			i++
+			continue
+		}
+
+		if s[i] == quote {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func unescape(s string) string {
+	r := strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`, `\$`, "\x00")
+
+	return r.Replace(s)
+}
@@ -0,0 +1,50 @@
+package dotenv_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/katallaxie/run/pkg/dotenv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	type test struct {
+		input    string
+		want     map[string]string
+		hasError bool
+	}
+
+	lookup := func(key string) (string, bool) {
+		if key == "HOME" {
+			return "/home/run", true
+		}
+
+		return "", false
+	}
+
+	tests := []test{
+		{input: "FOO=bar", want: map[string]string{"FOO": "bar"}},
+		{input: "export FOO=bar", want: map[string]string{"FOO": "bar"}},
+		{input: "# comment\n\nFOO = bar # comment", want: map[string]string{"FOO": "bar"}},
+		{input: "FOO='$HOME # not a comment'", want: map[string]string{"FOO": "$HOME # not a comment"}},
+		{input: `FOO="line\nbreak \"quoted\" \$HOME"`, want: map[string]string{"FOO": "line\nbreak \"quoted\" $HOME"}},
+		{input: "FOO=\"multi\nline\"", want: map[string]string{"FOO": "multi\nline"}},
+		{input: "FOO=bar\nBAR=${FOO}/baz\nDIR=$HOME/src", want: map[string]string{"FOO": "bar", "BAR": "bar/baz", "DIR": "/home/run/src"}},
+		{input: "FOO=", want: map[string]string{"FOO": ""}},
+		{input: "FOO", hasError: true},
+		{input: "FOO=\"unterminated", hasError: true},
+	}
+
+	for _, tc := range tests {
+		got, err := dotenv.Parse(strings.NewReader(tc.input), lookup)
+		if tc.hasError {
+			assert.Error(t, err)
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+}
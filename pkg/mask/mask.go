@@ -0,0 +1,163 @@
+package mask
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Placeholder is the replacement for a masked value.
+const Placeholder = "***"
+
+// MinLength is the length a value needs to be masked. Shorter values would mask
+// every occurrence of their characters in the output.
+const MinLength = 4
+
+// Masker ...
+type Masker struct {
+	values   map[string]struct{}
+	replacer *strings.Replacer
+
+	sync.RWMutex
+}
+
+// New ...
+func New() *Masker {
+	m := new(Masker)
+	m.values = make(map[string]struct{})
+	m.replacer = strings.NewReplacer()
+
+	return m
+}
+
+// Add adds values to be masked. Every value is also masked in its
+// base64 and URL-encoded forms, and line by line if it spans multiple lines.
+// Values and lines shorter than MinLength are ignored.
+func (m *Masker) Add(values ...string) {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, v := range values {
+		for _, vv := range variants(v) {
+			if len(strings.TrimSpace(vv)) < MinLength {
+				continue
+			}
+			m.values[vv] = struct{}{}
+		}
+	}
+
+	vv := make([]string, 0, len(m.values))
+	for v := range m.values {
+		vv = append(vv, v)
+	}
+
+	// longer values are replaced first
+	sort.Slice(vv, func(i, j int) bool {
+		if len(vv[i]) != len(vv[j]) {
+			return len(vv[i]) > len(vv[j])
+		}
+
+		return vv[i] < vv[j]
+	})
+
+	oldnew := make([]string, 0, len(vv)*2)
+	for _, v := range vv {
+		oldnew = append(oldnew, v, Placeholder)
+	}
+	m.replacer = strings.NewReplacer(oldnew...)
+}
+
+// Mask replaces all occurrences of the values in s.
+func (m *Masker) Mask(s string) string {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.replacer.Replace(s)
+}
+
+func (m *Masker) empty() bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	return len(m.values) == 0
+}
+
+// Writer returns a writer that masks all values before writing to w.
+func (m *Masker) Writer(w io.Writer) *Writer {
+	return &Writer{m: m, w: w}
+}
+
+// Writer is a line buffered writer that masks values.
+type Writer struct {
+	m   *Masker
+	w   io.Writer
+	buf []byte
+
+	sync.Mutex
+}
+
+// Write ...
+func (w *Writer) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	// nothing to mask, so the output is not buffered
+	if len(w.buf) == 0 && w.m.empty() {
+		return w.w.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+
+	idx := bytes.LastIndexByte(w.buf, '\n')
+	if idx < 0 {
+		return len(p), nil
+	}
+
+	if _, err := io.WriteString(w.w, w.m.Mask(string(w.buf[:idx+1]))); err != nil {
+		return 0, err
+	}
+	w.buf = append(w.buf[:0], w.buf[idx+1:]...)
+
+	return len(p), nil
+}
+
+// Flush writes the remaining buffered output.
+func (w *Writer) Flush() error {
+	w.Lock()
+	defer w.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	_, err := io.WriteString(w.w, w.m.Mask(string(w.buf)))
+	w.buf = w.buf[:0]
+
+	return err
+}
+
+func variants(v string) []string {
+	vv := []string{v}
+
+	if strings.Contains(v, "\n") {
+		for _, l := range strings.Split(v, "\n") {
+			vv = append(vv, strings.TrimRight(l, "\r"))
+		}
+	}
+
+	b := []byte(v)
+	vv = append(vv,
+		base64.StdEncoding.EncodeToString(b),
+		base64.RawStdEncoding.EncodeToString(b),
+		base64.URLEncoding.EncodeToString(b),
+		base64.RawURLEncoding.EncodeToString(b),
+		url.QueryEscape(v),
+		url.PathEscape(v),
+	)
+
+	return vv
+}
@@ -0,0 +1,57 @@
+package mask_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/url"
+	"testing"
+
+	"github.com/katallaxie/run/pkg/mask"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMasker_Mask(t *testing.T) {
+	secret := "s3cr3t/t0ken+"
+
+	m := mask.New()
+	assert.Equal(t, "token: "+secret, m.Mask("token: "+secret))
+
+	m.Add(secret, "multi\nline", "", "ab", "x\nyz")
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "token: " + secret, want: "token: ***"},
+		{input: base64.StdEncoding.EncodeToString([]byte(secret)), want: "***"},
+		{input: "https://example.com/?t=" + url.QueryEscape(secret), want: "https://example.com/?t=***"},
+		{input: "key: line", want: "key: ***"},
+		{input: "nothing to see", want: "nothing to see"},
+		{input: "ab xyz", want: "ab xyz"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, m.Mask(tc.input))
+	}
+}
+
+func TestWriter_Write(t *testing.T) {
+	m := mask.New()
+	m.Add("secret")
+
+	var out bytes.Buffer
+	w := m.Writer(&out)
+
+	_, err := w.Write([]byte("the sec"))
+	assert.NoError(t, err)
+	assert.Equal(t, "", out.String())
+
+	_, err = w.Write([]byte("ret is\nsecret"))
+	assert.NoError(t, err)
+	assert.Equal(t, "the *** is\n", out.String())
+
+	err = w.Flush()
+	assert.NoError(t, err)
+	assert.Equal(t, "the *** is\n***", out.String())
+}
@@ -0,0 +1,252 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	// Default writes the output of the tasks as is.
+	Default = ""
+	// Interleaved prefixes every line with the name of the task.
+	Interleaved = "interleaved"
+	// Group buffers the output of a task and writes it when the task has finished.
+	Group = "group"
+	// GitHub groups the output of a task with the workflow commands of GitHub Actions.
+	GitHub = "github"
+)
+
+// CloseFunc is called when a task has finished.
+type CloseFunc func() error
+
+// Output ...
+type Output interface {
+	// Writers returns the writers for the stdout and stderr of a task.
+	Writers(task string, stdout, stderr io.Writer) (io.Writer, io.Writer, CloseFunc)
+}
+
+// Opt ...
+type Opt func(*Opts)
+
+// Opts ...
+type Opts struct {
+	Color bool
+}
+
+// Configure ...
+func (o *Opts) Configure(opts ...Opt) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithColor enables colored prefixes for the interleaved output.
+func WithColor(color bool) Opt {
+	return func(o *Opts) {
+		o.Color = color
+	}
+}
+
+// New returns the output for the mode.
+func New(mode string, opts ...Opt) (Output, error) {
+	options := new(Opts)
+	options.Configure(opts...)
+
+	switch mode {
+	case Default:
+		return &defaultOutput{}, nil
+	case Interleaved:
+		return &interleavedOutput{color: options.Color, colors: make(map[string]string)}, nil
+	case Group:
+		return &groupOutput{}, nil
+	case GitHub:
+		return &groupOutput{
+			begin: func(task string) string { return fmt.Sprintf("::group::%s\n", task) },
+			end:   func(task string) string { return "::endgroup::\n" },
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown output mode: %s", mode)
+	}
+}
+
+type defaultOutput struct{}
+
+// Writers ...
+func (o *defaultOutput) Writers(task string, stdout, stderr io.Writer) (io.Writer, io.Writer, CloseFunc) {
+	return stdout, stderr, func() error { return nil }
+}
+
+var colors = []string{"32", "33", "34", "35", "36", "92", "93", "94", "95", "96"}
+
+type interleavedOutput struct {
+	color  bool
+	colors map[string]string
+
+	sync.Mutex
+}
+
+// Writers ...
+func (o *interleavedOutput) Writers(task string, stdout, stderr io.Writer) (io.Writer, io.Writer, CloseFunc) {
+	prefix := o.prefix(task)
+
+	// both writers share the lock of the output so that lines are written as a whole
+	out := &prefixWriter{w: stdout, prefix: prefix, mu: &o.Mutex}
+	err := &prefixWriter{w: stderr, prefix: prefix, mu: &o.Mutex}
+
+	return out, err, func() error {
+		if err := out.Flush(); err != nil {
+			return err
+		}
+
+		return err.Flush()
+	}
+}
+
+func (o *interleavedOutput) prefix(task string) string {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.color {
+		return fmt.Sprintf("[%s] ", task)
+	}
+
+	c, ok := o.colors[task]
+	if !ok {
+		c = colors[len(o.colors)%len(colors)]
+		o.colors[task] = c
+	}
+
+	return fmt.Sprintf("\x1b[%sm[%s]\x1b[0m ", c, task)
+}
+
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	buf    []byte
+	mu     *sync.Mutex
+}
+
+// Write ...
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+
+		if _, err := io.WriteString(w.w, w.prefix+string(w.buf[:idx+1])); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[idx+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush writes the remaining output as a line.
+func (w *prefixWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	_, err := io.WriteString(w.w, w.prefix+string(w.buf)+"\n")
+	w.buf = nil
+
+	return err
+}
+
+type groupOutput struct {
+	begin func(task string) string
+	end   func(task string) string
+
+	sync.Mutex
+}
+
+// Writers ...
+func (o *groupOutput) Writers(task string, stdout, stderr io.Writer) (io.Writer, io.Writer, CloseFunc) {
+	b := NewBuffer()
+
+	return b.Writer(stdout), b.Writer(stderr), func() error {
+		o.Lock()
+		defer o.Unlock()
+
+		if o.begin != nil {
+			if _, err := io.WriteString(stdout, o.begin(task)); err != nil {
+				return err
+			}
+		}
+
+		if err := b.Replay(); err != nil {
+			return err
+		}
+
+		if o.end != nil {
+			if _, err := io.WriteString(stdout, o.end(task)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+type chunk struct {
+	w io.Writer
+	p []byte
+}
+
+// Buffer keeps the output of multiple writers in order.
+type Buffer struct {
+	chunks []chunk
+
+	sync.Mutex
+}
+
+// NewBuffer ...
+func NewBuffer() *Buffer {
+	return new(Buffer)
+}
+
+// Writer returns a writer that buffers the output for w.
+func (b *Buffer) Writer(w io.Writer) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		b.Lock()
+		defer b.Unlock()
+
+		b.chunks = append(b.chunks, chunk{w: w, p: append([]byte(nil), p...)})
+
+		return len(p), nil
+	})
+}
+
+// Replay writes the output to the original writers.
+func (b *Buffer) Replay() error {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, c := range b.chunks {
+		if _, err := c.w.Write(c.p); err != nil {
+			return err
+		}
+	}
+	b.chunks = nil
+
+	return nil
+}
+
+type writerFunc func(p []byte) (int, error)
+
+// Write ...
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,48 @@
+package output_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/katallaxie/run/pkg/output"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	_, err := output.New("unknown")
+	assert.Error(t, err)
+}
+
+func TestOutput_Writers(t *testing.T) {
+	type test struct {
+		mode string
+		want string
+	}
+
+	tests := []test{
+		{mode: output.Default, want: "a1\nb1\nb2a2\n"},
+		{mode: output.Interleaved, want: "[a] a1\n[b] b1\n[a] a2\n[b] b2\n"},
+		{mode: output.Group, want: "b1\nb2a1\na2\n"},
+		{mode: output.GitHub, want: "::group::b\nb1\nb2::endgroup::\n::group::a\na1\na2\n::endgroup::\n"},
+	}
+
+	for _, tc := range tests {
+		o, err := output.New(tc.mode)
+		assert.NoError(t, err)
+
+		var out bytes.Buffer
+		a, _, closeA := o.Writers("a", &out, &out)
+		b, _, closeB := o.Writers("b", &out, &out)
+
+		_, _ = io.WriteString(a, "a1\n")
+		_, _ = io.WriteString(b, "b1\nb2")
+		_, _ = io.WriteString(a, "a2\n")
+
+		assert.NoError(t, closeB())
+		assert.NoError(t, closeA())
+
+		assert.Equal(t, tc.want, out.String(), tc.mode)
+	}
+}
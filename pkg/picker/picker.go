@@ -0,0 +1,373 @@
+package picker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrCanceled is returned if the picker is closed without a selection.
+var ErrCanceled = errors.New("picker canceled")
+
+// Item ...
+type Item struct {
+	// Name is the name that is matched and returned.
+	Name string
+	// Desc is the description that is shown next to the name.
+	Desc string
+	// Deps are the dependencies that are shown below the name.
+	Deps []string
+}
+
+// Opt ...
+type Opt func(*Opts)
+
+// Opts ...
+type Opts struct {
+	Prompt string
+	Height int
+	Color  bool
+}
+
+// Configure ...
+func (o *Opts) Configure(opts ...Opt) {
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.Prompt == "" {
+		o.Prompt = "> "
+	}
+
+	if o.Height < 1 {
+		o.Height = 10
+	}
+}
+
+// WithPrompt ...
+func WithPrompt(prompt string) Opt {
+	return func(o *Opts) {
+		o.Prompt = prompt
+	}
+}
+
+// WithHeight sets the number of items that are shown at the same time.
+func WithHeight(n int) Opt {
+	return func(o *Opts) {
+		o.Height = n
+	}
+}
+
+// WithColor highlights the matched characters and the cursor.
+func WithColor(color bool) Opt {
+	return func(o *Opts) {
+		o.Color = color
+	}
+}
+
+// Match reports if all runes of the query are in s in the same order, ignoring the case.
+// The score is lower for matches that are closer together and start earlier.
+func Match(query, s string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+
+	q := []rune(strings.ToLower(query))
+	score, first, last := 0, -1, -1
+
+	i := 0
+	for pos, r := range []rune(strings.ToLower(s)) {
+		if i == len(q) {
+			break
+		}
+
+		if r != q[i] {
+			continue
+		}
+
+		if first < 0 {
+			first = pos
+		}
+		if last >= 0 {
+			score += pos - last - 1
+		}
+		last = pos
+		i++
+	}
+
+	if i < len(q) {
+		return 0, false
+	}
+
+	return score + first, true
+}
+
+// Filter returns the items with a name that matches the query, the best matches first.
+func Filter(items []Item, query string) []Item {
+	type match struct {
+		item  Item
+		score int
+	}
+
+	matches := make([]match, 0, len(items))
+	for _, item := range items {
+		if score, ok := Match(query, item.Name); ok {
+			matches = append(matches, match{item, score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score < matches[j].score })
+
+	filtered := make([]Item, 0, len(matches))
+	for _, m := range matches {
+		filtered = append(filtered, m.item)
+	}
+
+	return filtered
+}
+
+// Picker is an interactive fuzzy search over items with multi-select.
+// The terminal must be in raw mode.
+type Picker struct {
+	items    []Item
+	opts     *Opts
+	query    []rune
+	matches  []Item
+	cursor   int
+	offset   int
+	selected map[string]bool
+	order    []string
+	lines    int
+}
+
+// New ...
+func New(items []Item, opts ...Opt) *Picker {
+	options := new(Opts)
+	options.Configure(opts...)
+
+	p := &Picker{
+		items:    items,
+		opts:     options,
+		selected: make(map[string]bool),
+	}
+	p.filter()
+
+	return p
+}
+
+// Run reads the keys from r and draws the picker to w until a selection is made.
+// Tab toggles the item under the cursor, enter returns the toggled items or
+// the item under the cursor if none is toggled. Esc and Ctrl-C cancel the picker.
+func (p *Picker) Run(r io.Reader, w io.Writer) ([]string, error) {
+	buf := make([]byte, 64)
+
+	for {
+		p.draw(w)
+
+		n, err := r.Read(buf)
+		if n == 0 && err != nil {
+			p.clear(w)
+			if errors.Is(err, io.EOF) {
+				return nil, ErrCanceled
+			}
+
+			return nil, err
+		}
+
+		done, err := p.keys(buf[:n])
+		if done || err != nil {
+			p.clear(w)
+			if err != nil {
+				return nil, err
+			}
+
+			return p.selection(), nil
+		}
+	}
+}
+
+// keys handles the keys of a single read. Escape sequences of the terminal are
+// sent in a single read, a single escape is a key.
+func (p *Picker) keys(b []byte) (bool, error) {
+	for len(b) > 0 {
+		switch {
+		case len(b) >= 3 && b[0] == 0x1b && (b[1] == '[' || b[1] == 'O'):
+			switch b[2] {
+			case 'A':
+				p.move(-1)
+			case 'B':
+				p.move(1)
+			}
+			b = b[3:]
+			continue
+		case b[0] == 0x1b && len(b) == 1, b[0] == 0x03:
+			return false, ErrCanceled
+		case b[0] == '\r', b[0] == '\n':
+			return len(p.matches) > 0 || len(p.order) > 0, nil
+		case b[0] == '\t':
+			p.toggle()
+		case b[0] == 0x10: // Ctrl-P
+			p.move(-1)
+		case b[0] == 0x0e: // Ctrl-N
+			p.move(1)
+		case b[0] == 0x7f, b[0] == 0x08:
+			if len(p.query) > 0 {
+				p.query = p.query[:len(p.query)-1]
+				p.filter()
+			}
+		case b[0] == 0x15: // Ctrl-U
+			p.query = p.query[:0]
+			p.filter()
+		default:
+			r, size := utf8.DecodeRune(b)
+			if unicode.IsPrint(r) {
+				p.query = append(p.query, r)
+				p.filter()
+			}
+			b = b[size:]
+			continue
+		}
+
+		b = b[1:]
+	}
+
+	return false, nil
+}
+
+func (p *Picker) filter() {
+	p.matches = Filter(p.items, string(p.query))
+	p.cursor, p.offset = 0, 0
+}
+
+func (p *Picker) move(n int) {
+	if len(p.matches) == 0 {
+		return
+	}
+
+	p.cursor = (p.cursor + n + len(p.matches)) % len(p.matches)
+
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	}
+	if p.cursor >= p.offset+p.opts.Height {
+		p.offset = p.cursor - p.opts.Height + 1
+	}
+}
+
+func (p *Picker) toggle() {
+	if len(p.matches) == 0 {
+		return
+	}
+
+	name := p.matches[p.cursor].Name
+	if p.selected[name] {
+		delete(p.selected, name)
+		for i, n := range p.order {
+			if n == name {
+				p.order = append(p.order[:i], p.order[i+1:]...)
+				break
+			}
+		}
+	} else {
+		p.selected[name] = true
+		p.order = append(p.order, name)
+	}
+
+	p.move(1)
+}
+
+func (p *Picker) selection() []string {
+	if len(p.order) > 0 {
+		return append([]string{}, p.order...)
+	}
+
+	return []string{p.matches[p.cursor].Name}
+}
+
+// clear removes the lines that have been drawn.
+func (p *Picker) clear(w io.Writer) {
+	if p.lines > 0 {
+		fmt.Fprintf(w, "\x1b[%dA", p.lines)
+	}
+	fmt.Fprint(w, "\r\x1b[J")
+	p.lines = 0
+}
+
+func (p *Picker) draw(w io.Writer) {
+	var b strings.Builder
+
+	lines := 0
+	end := p.offset + p.opts.Height
+	if end > len(p.matches) {
+		end = len(p.matches)
+	}
+
+	for i := p.offset; i < end; i++ {
+		item := p.matches[i]
+
+		cursor, mark := "  ", "[ ]"
+		if i == p.cursor {
+			cursor = p.style("1", "> ")
+		}
+		if p.selected[item.Name] {
+			mark = "[x]"
+		}
+
+		fmt.Fprintf(&b, "%s%s %s", cursor, mark, p.highlight(item.Name))
+		if item.Desc != "" {
+			fmt.Fprintf(&b, " %s", p.style("2", item.Desc))
+		}
+		b.WriteString("\r\n")
+		lines++
+
+		if i == p.cursor && len(item.Deps) > 0 {
+			fmt.Fprintf(&b, "        %s\r\n", p.style("2", "depends on: "+strings.Join(item.Deps, ", ")))
+			lines++
+		}
+	}
+
+	fmt.Fprintf(&b, "  %d/%d", len(p.matches), len(p.items))
+	if len(p.order) > 0 {
+		fmt.Fprintf(&b, " (%d selected)", len(p.order))
+	}
+	fmt.Fprintf(&b, "\r\n%s%s", p.opts.Prompt, string(p.query))
+	lines++
+
+	p.clear(w)
+	fmt.Fprint(w, b.String())
+	p.lines = lines
+}
+
+// highlight marks the characters of the name that match the query.
+func (p *Picker) highlight(name string) string {
+	if !p.opts.Color || len(p.query) == 0 {
+		return name
+	}
+
+	q := []rune(strings.ToLower(string(p.query)))
+
+	var b strings.Builder
+	i := 0
+	for _, r := range name {
+		if i < len(q) && unicode.ToLower(r) == q[i] {
+			b.WriteString(p.style("1;36", string(r)))
+			i++
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func (p *Picker) style(code, s string) string {
+	if !p.opts.Color {
+		return s
+	}
+
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
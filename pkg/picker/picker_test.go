@@ -0,0 +1,81 @@
+package picker_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/katallaxie/run/pkg/picker"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// keys returns the keys in separate reads like a terminal in raw mode.
+type keys []string
+
+func (k *keys) Read(b []byte) (int, error) {
+	if len(*k) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(b, (*k)[0])
+	*k = (*k)[1:]
+
+	return n, nil
+}
+
+func TestFilter(t *testing.T) {
+	items := []picker.Item{{Name: "build"}, {Name: "lint"}, {Name: "test-unit"}, {Name: "tests"}}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "", want: []string{"build", "lint", "test-unit", "tests"}},
+		{query: "t", want: []string{"test-unit", "tests", "lint"}},
+		{query: "tss", want: []string{"tests"}},
+		{query: "TU", want: []string{"test-unit"}},
+		{query: "bl", want: []string{"build"}},
+		{query: "xyz", want: []string{}},
+	}
+
+	for _, tc := range tests {
+		names := []string{}
+		for _, item := range picker.Filter(items, tc.query) {
+			names = append(names, item.Name)
+		}
+		assert.Equal(t, tc.want, names, tc.query)
+	}
+}
+
+func TestPicker_Run(t *testing.T) {
+	items := []picker.Item{
+		{Name: "build", Desc: "build the app"},
+		{Name: "lint"},
+		{Name: "test", Deps: []string{"build"}},
+	}
+
+	tests := []struct {
+		name string
+		keys keys
+		want []string
+		err  error
+	}{
+		{name: "cursor", keys: keys{"\x1b[B", "\x1b[B", "\r"}, want: []string{"test"}},
+		{name: "wrap", keys: keys{"\x1b[A", "\r"}, want: []string{"test"}},
+		{name: "query", keys: keys{"l", "i", "\r"}, want: []string{"lint"}},
+		{name: "backspace", keys: keys{"x", "\x7f", "\r"}, want: []string{"build"}},
+		{name: "multi-select", keys: keys{"te", "\t", "\x15", "\t", "\r"}, want: []string{"test", "build"}},
+		{name: "untoggle", keys: keys{"\t", "\x1b[A", "\t", "\r"}, want: []string{"lint"}},
+		{name: "no match", keys: keys{"xyz", "\r", "\x1b"}, err: picker.ErrCanceled},
+		{name: "ctrl-c", keys: keys{"\x03"}, err: picker.ErrCanceled},
+		{name: "eof", keys: keys{"b"}, err: picker.ErrCanceled},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := picker.New(items).Run(&tc.keys, io.Discard)
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/go-hclog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// zapSink forwards the logs of the plugin host to a zap logger.
+type zapSink struct {
+	logger *zap.Logger
+}
+
+// Accept ...
+func (s *zapSink) Accept(name string, level hclog.Level, msg string, args ...interface{}) {
+	fields := make([]zap.Field, 0, len(args)/2+1)
+	fields = append(fields, zap.String("plugin", name))
+
+	for i := 0; i+1 < len(args); i += 2 {
+		fields = append(fields, zap.Any(fmt.Sprint(args[i]), args[i+1]))
+	}
+
+	switch level {
+	case hclog.Trace, hclog.Debug:
+		s.logger.Debug(msg, fields...)
+	case hclog.Info:
+		s.logger.Info(msg, fields...)
+	case hclog.Warn:
+		s.logger.Warn(msg, fields...)
+	default:
+		s.logger.Error(msg, fields...)
+	}
+}
+
+func newLogger(name string, logger *zap.Logger) hclog.Logger {
+	l := hclog.NewInterceptLogger(&hclog.LoggerOptions{
+		Name:   name,
+		Level:  level(logger),
+		Output: io.Discard,
+	})
+	l.RegisterSink(&zapSink{logger: logger})
+
+	return l
+}
+
+func level(logger *zap.Logger) hclog.Level {
+	switch {
+	case logger.Core().Enabled(zapcore.DebugLevel):
+		return hclog.Debug
+	case logger.Core().Enabled(zapcore.InfoLevel):
+		return hclog.Info
+	case logger.Core().Enabled(zapcore.WarnLevel):
+		return hclog.Warn
+	default:
+		return hclog.Error
+	}
+}
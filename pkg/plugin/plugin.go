@@ -2,11 +2,12 @@ package plugin
 
 import (
 	"context"
+	"io"
 	"os"
 	"os/exec"
 
-	"github.com/hashicorp/go-hclog"
 	p "github.com/hashicorp/go-plugin"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
 
 	"github.com/katallaxie/run/pkg/proto"
@@ -20,6 +21,12 @@ type Meta struct {
 	Path string
 	// Arguments ...
 	Arguments []string
+	// Logger is used for the logs of the plugin host.
+	Logger *zap.Logger
+	// Stdout is the writer for the stdout of the plugin.
+	Stdout io.Writer
+	// Stderr is the writer for the stderr of the plugin.
+	Stderr io.Writer
 }
 
 // ExecutableFile ...
@@ -75,12 +82,12 @@ func (p *GRPCPlugin) Execute(req ExecuteRequest) (ExecuteResponse, error) {
 	r.Vars = req.Vars
 	r.Args = req.Arguments
 
-	_, err := p.client.Execute(p.ctx, r)
+	resp, err := p.client.Execute(p.ctx, r)
 	if err != nil {
 		return ExecuteResponse{}, err
 	}
 
-	return ExecuteResponse{}, nil
+	return ExecuteResponse{}, proto.ErrorFromDiagnostics(resp.GetDiagnostic())
 }
 
 // Factory ...
@@ -111,10 +118,11 @@ func pluginFactory(ctx context.Context, meta *Meta) Factory {
 			return nil, err
 		}
 
-		l := hclog.New(&hclog.LoggerOptions{
-			Name:  meta.Path,
-			Level: hclog.LevelFromString("DEBUG"),
-		})
+		logger := meta.Logger
+		if logger == nil {
+			logger = zap.NewNop()
+		}
+		l := newLogger(meta.Path, logger)
 
 		cfg := &p.ClientConfig{
 			Logger:           l,
@@ -124,8 +132,8 @@ func pluginFactory(ctx context.Context, meta *Meta) Factory {
 			Managed:          true,
 			AllowedProtocols: []p.Protocol{p.ProtocolGRPC},
 			Cmd:              exec.CommandContext(ctx, f, meta.Arguments...),
-			SyncStderr:       l.StandardWriter(&hclog.StandardLoggerOptions{}),
-			SyncStdout:       l.StandardWriter(&hclog.StandardLoggerOptions{}),
+			SyncStderr:       meta.Stderr,
+			SyncStdout:       meta.Stdout,
 		}
 		client := p.NewClient(cfg)
 
@@ -1,5 +1,10 @@
 package proto
 
+import (
+	"fmt"
+	"strings"
+)
+
 // DiagnosticFromError ...
 func DiagnosticFromError(err error) *Diagnostic {
 	return &Diagnostic{
@@ -7,3 +12,40 @@ func DiagnosticFromError(err error) *Diagnostic {
 		Summary:  err.Error(),
 	}
 }
+
+// DiagnosticsError is the error of the diagnostics with the ERROR severity.
+type DiagnosticsError []*Diagnostic
+
+// Error ...
+func (e DiagnosticsError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, d := range e {
+		msg := d.GetSummary()
+		if d.GetDetail() != "" {
+			msg += ": " + d.GetDetail()
+		}
+		if len(d.GetFilePaths()) > 0 {
+			msg += fmt.Sprintf(" (%s)", strings.Join(d.GetFilePaths(), ", "))
+		}
+		msgs = append(msgs, msg)
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// ErrorFromDiagnostics returns the diagnostics with the ERROR severity as error,
+// or nil if there are none.
+func ErrorFromDiagnostics(diags []*Diagnostic) error {
+	var errs DiagnosticsError
+	for _, d := range diags {
+		if d.GetSeverity() == Diagnostic_ERROR {
+			errs = append(errs, d)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
@@ -0,0 +1,107 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the report in the JUnit XML format.
+// Every task is a test suite and every step is a test case.
+// A task without steps is a test case of its own.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	r.Lock()
+	defer r.Unlock()
+
+	suites := junitTestSuites{Name: "run", Time: seconds(r.Duration)}
+
+	for _, t := range r.Tasks {
+		suite := junitTestSuite{Name: t.Name, Time: seconds(t.Duration)}
+
+		steps := t.Steps
+		if len(steps) == 0 {
+			steps = []*Step{{Name: t.Name, Status: t.Status, Duration: t.Duration, Error: t.Error}}
+		}
+
+		for _, s := range steps {
+			c := junitTestCase{Name: s.Name, Classname: t.Name, Time: seconds(s.Duration)}
+
+			switch s.Status {
+			case StatusFailed:
+				c.Failure = &junitFailure{
+					Message: s.Error,
+					Type:    fmt.Sprintf("exit code %d", s.ExitCode),
+					Text:    fmt.Sprintf("%s (retries: %d)", s.Error, s.Retries),
+				}
+				suite.Failures++
+			case StatusSkipped, StatusCached, StatusUpToDate:
+				c.Skipped = &junitSkipped{Message: string(s.Status)}
+				suite.Skipped++
+			}
+
+			suite.Cases = append(suite.Cases, c)
+			suite.Tests++
+		}
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
+
+func seconds(d Duration) string {
+	return fmt.Sprintf("%.3f", time.Duration(d).Seconds())
+}
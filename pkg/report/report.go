@@ -0,0 +1,165 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Status ...
+type Status string
+
+const (
+	// StatusSuccess ...
+	StatusSuccess Status = "success"
+	// StatusFailed ...
+	StatusFailed Status = "failed"
+	// StatusSkipped is the status of a task or step that has not run.
+	StatusSkipped Status = "skipped"
+	// StatusCached is the status of a task whose outputs are restored from the cache.
+	StatusCached Status = "cached"
+	// StatusUpToDate is the status of a task whose status commands have succeeded.
+	StatusUpToDate Status = "up-to-date"
+)
+
+// Duration is a duration that is encoded as seconds in JSON.
+type Duration time.Duration
+
+// MarshalJSON ...
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).Seconds())
+}
+
+// UnmarshalJSON ...
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s float64
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*d = Duration(s * float64(time.Second))
+
+	return nil
+}
+
+// String ...
+func (d Duration) String() string {
+	return time.Duration(d).Round(time.Millisecond).String()
+}
+
+// Report is the report of a run with all tasks and steps.
+type Report struct {
+	Tasks    []*Task  `json:"tasks"`
+	Duration Duration `json:"duration"`
+
+	sync.Mutex
+}
+
+// New ...
+func New() *Report {
+	return &Report{Tasks: make([]*Task, 0)}
+}
+
+// AddTask adds a task that is skipped until it has run.
+func (r *Report) AddTask(name string) *Task {
+	if r == nil {
+		return nil
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	t := &Task{Name: name, Status: StatusSkipped, Steps: make([]*Step, 0)}
+	r.Tasks = append(r.Tasks, t)
+
+	return t
+}
+
+// Task ...
+type Task struct {
+	Name     string   `json:"name"`
+	Status   Status   `json:"status"`
+	Duration Duration `json:"duration"`
+	Error    string   `json:"error,omitempty"`
+	Steps    []*Step  `json:"steps"`
+}
+
+// AddStep adds a step that is skipped until it has run.
+// The step is not recorded if the task is nil.
+func (t *Task) AddStep(name string) *Step {
+	s := &Step{Name: name, Status: StatusSkipped}
+	if t != nil {
+		t.Steps = append(t.Steps, s)
+	}
+
+	return s
+}
+
+// Finish sets the status and duration of the task.
+func (t *Task) Finish(status Status, d time.Duration, err error) {
+	if t == nil {
+		return
+	}
+
+	t.Status, t.Duration, t.Error = status, Duration(d), errString(err)
+}
+
+// Step ...
+type Step struct {
+	Name     string   `json:"name"`
+	Status   Status   `json:"status"`
+	Duration Duration `json:"duration"`
+	ExitCode int      `json:"exit_code"`
+	Error    string   `json:"error,omitempty"`
+	Retries  int      `json:"retries"`
+	// Env are the names of the environment variables of the step.
+	Env []string `json:"env,omitempty"`
+}
+
+// Finish sets the status and duration of the step.
+func (s *Step) Finish(status Status, d time.Duration, exitCode int, err error) {
+	s.Status, s.Duration, s.ExitCode, s.Error = status, Duration(d), exitCode, errString(err)
+}
+
+// WriteJSON ...
+func (r *Report) WriteJSON(w io.Writer) error {
+	r.Lock()
+	defer r.Unlock()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(r)
+}
+
+// WriteTable writes a summary of the tasks as a table.
+func (r *Report) WriteTable(w io.Writer) error {
+	r.Lock()
+	defer r.Unlock()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK\tSTATUS\tDURATION\tSTEPS\tRETRIES")
+	for _, t := range r.Tasks {
+		finished, retries := 0, 0
+		for _, s := range t.Steps {
+			if s.Status != StatusSkipped {
+				finished++
+			}
+			retries += s.Retries
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d/%d\t%d\n", t.Name, t.Status, t.Duration, finished, len(t.Steps), retries)
+	}
+
+	return tw.Flush()
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
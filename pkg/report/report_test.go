@@ -0,0 +1,63 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katallaxie/run/pkg/report"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newReport() *report.Report {
+	r := report.New()
+
+	build := r.AddTask("build")
+	build.AddStep("compile").Finish(report.StatusSuccess, time.Second, 0, nil)
+	build.Finish(report.StatusSuccess, time.Second, nil)
+
+	test := r.AddTask("test")
+	s := test.AddStep("unit")
+	s.Retries = 2
+	s.Finish(report.StatusFailed, 2*time.Second, 1, errors.New("exit status 1"))
+	test.AddStep("e2e")
+	test.Finish(report.StatusFailed, 2*time.Second, errors.New("exit status 1"))
+
+	r.AddTask("release")
+
+	return r
+}
+
+func TestReport_WriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := newReport().WriteJSON(&buf)
+	assert.NoError(t, err)
+
+	r := report.New()
+	err = json.Unmarshal(buf.Bytes(), r)
+	assert.NoError(t, err)
+
+	assert.Len(t, r.Tasks, 3)
+	assert.Equal(t, report.StatusFailed, r.Tasks[1].Status)
+	assert.Equal(t, report.Duration(2*time.Second), r.Tasks[1].Steps[0].Duration)
+	assert.Equal(t, 2, r.Tasks[1].Steps[0].Retries)
+	assert.Equal(t, report.StatusSkipped, r.Tasks[1].Steps[1].Status)
+	assert.Equal(t, report.StatusSkipped, r.Tasks[2].Status)
+}
+
+func TestReport_WriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	err := newReport().WriteJUnit(&buf)
+	assert.NoError(t, err)
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, `<?xml version="1.0" encoding="UTF-8"?>`))
+	assert.Contains(t, out, `<testsuites name="run" tests="4" failures="1" skipped="2" time="0.000">`)
+	assert.Contains(t, out, `<testcase name="unit" classname="test" time="2.000">`)
+	assert.Contains(t, out, `<failure message="exit status 1" type="exit code 1">`)
+	assert.Contains(t, out, `<testcase name="release" classname="release" time="0.000">`)
+}
@@ -0,0 +1,170 @@
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/katallaxie/run/pkg/cache"
+	"github.com/katallaxie/run/pkg/spec"
+	"github.com/katallaxie/run/pkg/utils"
+
+	"gopkg.in/yaml.v3"
+)
+
+// cacheKey returns the hash of the inputs of a job. These are the definition
+// of the task, the declared environment including the env files, the variables,
+// the arguments, and the contents of the sources.
+func (r *Runner) cacheKey(j job) (string, error) {
+	t := r.opts.File.Tasks[j.task]
+
+	h := sha256.New()
+	fmt.Fprintf(h, "run cache v1\ntask %s\n", j.name)
+
+	def, err := yaml.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	h.Write(def)
+
+	// the env files are expanded with the inherited environment like in a run
+	environ := spec.Env(utils.Environ())
+	inherit := r.opts.File.EnvInherit
+	if t.EnvInherit != nil {
+		inherit = t.EnvInherit
+	}
+
+	env := make(spec.Env)
+	if err := r.opts.File.EnvFile.Load(r.opts.WorkingDir, env, r.opts.File.EnvInherit.Filter(environ)); err != nil {
+		return "", err
+	}
+	env.Merge(r.opts.File.Env)
+	if err := t.EnvFile.Load(r.opts.WorkingDir, env, inherit.Filter(environ)); err != nil {
+		return "", err
+	}
+	env.Merge(t.Env)
+	if t.Matrix != nil {
+		env.Merge(t.Matrix.Env(j.matrix))
+	}
+	env.Merge(spec.Env(r.opts.Env))
+
+	vars := make(spec.Vars)
+	vars.Merge(r.opts.File.Vars)
+	vars.Merge(t.Vars)
+	vars.Merge(j.matrix)
+	vars.Merge(spec.Vars(r.opts.Vars))
+
+	fmt.Fprintf(h, "env %q\nvars %q\n", utils.Strings(env), utils.Strings(vars))
+
+	for i, s := range append(append(spec.Steps{}, t.Steps...), t.Finally...) {
+		if len(s.EnvFile) == 0 {
+			continue
+		}
+
+		dir := r.opts.WorkingDir
+		if s.WorkingDir != "" {
+			dir = s.WorkingDir
+		}
+
+		sinherit := inherit
+		if s.EnvInherit != nil {
+			sinherit = s.EnvInherit
+		}
+
+		env := make(spec.Env)
+		if err := s.EnvFile.Load(dir, env, sinherit.Filter(environ)); err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(h, "step %d env %q\n", i, utils.Strings(env))
+	}
+
+	if t.AcceptsArgs {
+		fmt.Fprintf(h, "args %q\n", r.opts.Args)
+	}
+
+	files, err := cache.Glob(r.opts.WorkingDir.String(), t.Sources)
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range files {
+		if err := hashFile(h, r.opts.WorkingDir.String(), name); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(w io.Writer, dir, name string) error {
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "source %s %x\n", name, h.Sum(nil))
+
+	return err
+}
+
+// restore extracts the outputs of a job from the cache.
+// It returns false if there is no entry for the key.
+func (r *Runner) restore(ctx context.Context, key string) (bool, error) {
+	rc, err := r.opts.Cache.Get(ctx, key)
+	if errors.Is(err, cache.ErrNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+	defer rc.Close()
+
+	if _, err := cache.Unpack(rc, r.opts.WorkingDir.String()); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// save stores the files that match the generates of a task in the cache.
+func (r *Runner) save(ctx context.Context, key string, generates []string) error {
+	files, err := cache.Glob(r.opts.WorkingDir.String(), generates)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp("", "run-cache-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if err := cache.Pack(f, r.opts.WorkingDir.String(), files); err != nil {
+		return err
+	}
+
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	return r.opts.Cache.Put(ctx, key, f, size)
+}
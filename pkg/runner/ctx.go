@@ -78,7 +78,7 @@ func (c *Ctx) Context() context.Context {
 
 // Env ...
 func (c *Ctx) Env() []string {
-	env := make([]string, len(c.env))
+	env := make([]string, 0, len(c.env))
 	for k, v := range c.env {
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
 	}
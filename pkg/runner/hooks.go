@@ -0,0 +1,90 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/katallaxie/run/pkg/spec"
+
+	"go.uber.org/zap"
+)
+
+// hooks returns the on-success or on-failure hooks.
+func hooks(err error, onSuccess, onFailure []string) []string {
+	if err != nil {
+		return onFailure
+	}
+
+	return onSuccess
+}
+
+// checkHooks returns an error if a hook of the spec or the tasks does not exist.
+func (r *Runner) checkHooks(tasks []string) error {
+	names := append(append([]string{}, r.opts.File.OnSuccess...), r.opts.File.OnFailure...)
+	for _, task := range tasks {
+		t := r.opts.File.Tasks[task]
+		names = append(append(names, t.OnSuccess...), t.OnFailure...)
+	}
+
+	for _, name := range names {
+		if _, ok := r.opts.File.Tasks[name]; !ok {
+			return fmt.Errorf("hook %s: task %s not found", name, name)
+		}
+	}
+
+	if _, err := r.opts.File.Find(names...); err != nil {
+		return fmt.Errorf("hooks: %w", err)
+	}
+
+	return nil
+}
+
+// runHooks runs the hooks of a task that has finished with err and their dependencies,
+// one after another. The hooks get the name and the outcome of the task as variables,
+// which is canceled if ctx is done. The hooks run with the context of the runner, so that
+// they are not canceled with the other tasks when a task fails.
+// Errors of the hooks are logged, and do not change the outcome of the task.
+func (r *Runner) runHooks(ctx context.Context, names []string, task string, err error, vars spec.Vars, opts []spec.RunOpt) {
+	if len(names) == 0 {
+		return
+	}
+
+	names, ferr := r.opts.File.Find(names...)
+	if ferr != nil {
+		r.opts.Logger.Warn("hook failed", zap.String("task", task), zap.Error(ferr))
+		return
+	}
+
+	hv := make(spec.Vars)
+	hv.Merge(vars)
+	hv.Merge(spec.Outcome(ctx, err))
+	hv["TASK_NAME"] = task
+
+	opts = append(opts[:len(opts):len(opts)], spec.WithExtraVars(hv))
+
+	for _, j := range r.jobs(names) {
+		j.report = r.opts.Report.AddTask(j.name)
+
+		if err := r.runTask(r.ctx, j, opts...); err != nil {
+			r.opts.Logger.Warn("hook failed", zap.String("task", task), zap.String("hook", j.name), zap.Error(err))
+		}
+	}
+}
+
+// failure records the first failed task of a run.
+type failure struct {
+	task string
+	err  error
+
+	sync.Mutex
+}
+
+func (f *failure) set(task string, err error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.err == nil && err != nil {
+		f.task, f.err = task, err
+	}
+}
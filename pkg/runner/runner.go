@@ -2,21 +2,36 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/katallaxie/run/pkg/cache"
+	"github.com/katallaxie/run/pkg/mask"
+	"github.com/katallaxie/run/pkg/output"
+	"github.com/katallaxie/run/pkg/report"
 	"github.com/katallaxie/run/pkg/spec"
+	"github.com/katallaxie/run/pkg/utils"
+
+	"go.uber.org/zap"
 )
 
+// ErrArgsNotAccepted is returned if there are arguments after `--`
+// but none of the tasks accepts arguments.
+var ErrArgsNotAccepted = errors.New("arguments are not accepted by the tasks")
+
 // Runner ...
 type Runner struct {
-	ctx   context.Context
-	funcs []RunFunc
-	pool  sync.Pool
-	opts  *Opts
+	ctx    context.Context
+	funcs  []RunFunc
+	pool   sync.Pool
+	opts   *Opts
+	stdout *mask.Writer
+	stderr *mask.Writer
 
 	sync.Mutex
 }
@@ -26,14 +41,27 @@ type Opt func(*Opts)
 
 // Opts ...
 type Opts struct {
-	Stdin      io.Reader
-	Stdout     io.Writer
-	Stderr     io.Writer
-	Timeout    time.Duration
-	File       *spec.Spec
-	Vars       Vars
-	Env        Env
-	WorkingDir spec.WorkingDir
+	Stdin       io.Reader
+	Stdout      io.Writer
+	Stderr      io.Writer
+	Timeout     time.Duration
+	File        *spec.Spec
+	Vars        Vars
+	Env         Env
+	Args        []string
+	Report      *report.Report
+	Cache       cache.Store
+	WorkingDir  spec.WorkingDir
+	Masker      *mask.Masker
+	Logger      *zap.Logger
+	Output      output.Output
+	Concurrency int
+	Dry         bool
+	Silent      bool
+	TermSignal  os.Signal
+	KillSignal  os.Signal
+	GracePeriod time.Duration
+	Kill        <-chan struct{}
 }
 
 // Configure ...
@@ -53,6 +81,22 @@ func (o *Opts) Configure(opts ...Opt) {
 	if o.Stderr == nil {
 		o.Stderr = os.Stderr
 	}
+
+	if o.Masker == nil {
+		o.Masker = mask.New()
+	}
+
+	if o.Logger == nil {
+		o.Logger = zap.NewNop()
+	}
+
+	if o.Output == nil {
+		o.Output, _ = output.New(output.Default)
+	}
+
+	if o.Concurrency < 1 {
+		o.Concurrency = 1
+	}
 }
 
 // WithSpec ...
@@ -62,6 +106,97 @@ func WithSpec(file *spec.Spec) Opt {
 	}
 }
 
+// WithStdin ...
+func WithStdin(r io.Reader) Opt {
+	return func(o *Opts) {
+		o.Stdin = r
+	}
+}
+
+// WithStdout ...
+func WithStdout(w io.Writer) Opt {
+	return func(o *Opts) {
+		o.Stdout = w
+	}
+}
+
+// WithStderr ...
+func WithStderr(w io.Writer) Opt {
+	return func(o *Opts) {
+		o.Stderr = w
+	}
+}
+
+// WithMasker ...
+func WithMasker(m *mask.Masker) Opt {
+	return func(o *Opts) {
+		o.Masker = m
+	}
+}
+
+// WithLogger ...
+func WithLogger(logger *zap.Logger) Opt {
+	return func(o *Opts) {
+		o.Logger = logger
+	}
+}
+
+// WithOutput ...
+func WithOutput(o output.Output) Opt {
+	return func(opts *Opts) {
+		opts.Output = o
+	}
+}
+
+// WithConcurrency sets the number of tasks that run at the same time.
+func WithConcurrency(n int) Opt {
+	return func(o *Opts) {
+		o.Concurrency = n
+	}
+}
+
+// WithTermSignal ...
+func WithTermSignal(sig os.Signal) Opt {
+	return func(o *Opts) {
+		o.TermSignal = sig
+	}
+}
+
+// WithKillSignal ...
+func WithKillSignal(sig os.Signal) Opt {
+	return func(o *Opts) {
+		o.KillSignal = sig
+	}
+}
+
+// WithGracePeriod ...
+func WithGracePeriod(d time.Duration) Opt {
+	return func(o *Opts) {
+		o.GracePeriod = d
+	}
+}
+
+// WithKill sets a channel that kills the processes of the running steps when it is closed.
+func WithKill(kill <-chan struct{}) Opt {
+	return func(o *Opts) {
+		o.Kill = kill
+	}
+}
+
+// WithSilent ...
+func WithSilent(silent bool) Opt {
+	return func(o *Opts) {
+		o.Silent = silent
+	}
+}
+
+// WithDry ...
+func WithDry(dry bool) Opt {
+	return func(o *Opts) {
+		o.Dry = dry
+	}
+}
+
 // WithVars ...
 func WithVars(vars Vars) Opt {
 	return func(o *Opts) {
@@ -76,6 +211,27 @@ func WithEnv(env Env) Opt {
 	}
 }
 
+// WithArgs sets the arguments after `--` on the command line.
+func WithArgs(args []string) Opt {
+	return func(o *Opts) {
+		o.Args = args
+	}
+}
+
+// WithReport sets the report the tasks and steps are recorded in.
+func WithReport(r *report.Report) Opt {
+	return func(o *Opts) {
+		o.Report = r
+	}
+}
+
+// WithCache sets the store of the outputs of the tasks with `generates`.
+func WithCache(store cache.Store) Opt {
+	return func(o *Opts) {
+		o.Cache = store
+	}
+}
+
 // WithWorkingDir ...
 func WithWorkingDir(cwd string) Opt {
 	return func(o *Opts) {
@@ -105,12 +261,28 @@ func (r *Runner) Stdin() io.Reader {
 
 // Stdout ...
 func (r *Runner) Stdout() io.Writer {
-	return r.opts.Stdout
+	if r.stdout == nil {
+		return r.opts.Stdout
+	}
+
+	return r.stdout
 }
 
 // Stderr ...
 func (r *Runner) Stderr() io.Writer {
-	return r.opts.Stderr
+	if r.stderr == nil {
+		return r.opts.Stderr
+	}
+
+	return r.stderr
+}
+
+// flush writes the output that is buffered for masking.
+func (r *Runner) flush() {
+	if r.stdout != nil {
+		_ = r.stdout.Flush()
+		_ = r.stderr.Flush()
+	}
 }
 
 // ReleaseFunc ...
@@ -126,31 +298,178 @@ type RunFunc func(c *Ctx) error
 
 // RunTask ...
 func (r *Runner) RunTasks(tasks ...string) error {
-	tt := make([]spec.Task, len(tasks))
-
-	for i, task := range tasks {
+	accepts := false
+	for _, task := range tasks {
 		t, ok := r.opts.File.Tasks[task]
 		if !ok {
 			return fmt.Errorf("task %s not found", task)
 		}
-		tt[i] = t
-	}
-
-	for _, t := range tt {
-		c := r.AcquireCtx()
-		defer r.ReleaseCtx(c)
-
-		if err := t.Run(
-			c.Context(),
-			spec.WithWorkingDir(r.opts.WorkingDir),
-			spec.WithExtraVars(r.opts.File.Vars),
-			spec.WithExtraEnv(r.opts.File.Env),
-			spec.WithStderr(c.runner.Stderr()),
-			spec.WithStdin(c.runner.Stdin()),
-			spec.WithStdout(c.runner.Stdout()),
-		); err != nil {
+		accepts = accepts || t.AcceptsArgs
+	}
+
+	if len(r.opts.Args) > 0 && !accepts {
+		return fmt.Errorf("%w: %s", ErrArgsNotAccepted, strings.Join(tasks, ", "))
+	}
+
+	if err := r.checkHooks(tasks); err != nil {
+		return err
+	}
+
+	environ := spec.Env(utils.Environ())
+
+	env := make(spec.Env)
+	if err := r.opts.File.EnvFile.Load(r.opts.WorkingDir, env, r.opts.File.EnvInherit.Filter(environ)); err != nil {
+		return err
+	}
+	env.Merge(r.opts.File.Env)
+
+	defer r.flush()
+
+	// the commands of the secrets run with the environment of the spec
+	senv := r.opts.File.EnvInherit.Filter(environ)
+	senv.Merge(env)
+
+	secrets, err := r.opts.File.Secrets.Resolve(
+		r.ctx,
+		spec.WithWorkingDir(r.opts.WorkingDir),
+		spec.WithExtraEnv(senv),
+		spec.WithStderr(r.Stderr()),
+		spec.WithDry(r.opts.Dry),
+	)
+	if err != nil {
+		return err
+	}
+
+	for name, v := range secrets {
+		if len(strings.TrimSpace(v)) < mask.MinLength {
+			r.opts.Logger.Warn("secret is too short to be masked", zap.String("secret", name))
+		}
+		r.opts.Masker.Add(v)
+	}
+	env.Merge(secrets)
+
+	vars := make(spec.Vars)
+	vars.Merge(r.opts.File.Vars)
+
+	opts := []spec.RunOpt{
+		spec.WithWorkingDir(r.opts.WorkingDir),
+		spec.WithExtraVars(vars),
+		spec.WithExtraEnv(env),
+		spec.WithEnviron(environ),
+		spec.WithEnvInherit(r.opts.File.EnvInherit),
+		spec.WithOverrideVars(spec.Vars(r.opts.Vars)),
+		spec.WithOverrideEnv(spec.Env(r.opts.Env)),
+		spec.WithShell(r.opts.File.Shell),
+		spec.WithArgs(r.opts.Args),
+		spec.WithDry(r.opts.Dry),
+		spec.WithSilent(r.opts.Silent),
+		spec.WithMasker(r.opts.Masker),
+		spec.WithTermSignal(r.opts.TermSignal),
+		spec.WithKillSignal(r.opts.KillSignal),
+		spec.WithGracePeriod(r.opts.GracePeriod),
+		spec.WithKill(r.opts.Kill),
+		spec.WithStdin(r.Stdin()),
+	}
+
+	jobs := r.jobs(tasks)
+	for i := range jobs {
+		jobs[i].report = r.opts.Report.AddTask(jobs[i].name)
+	}
+
+	if r.opts.Report != nil {
+		start := time.Now()
+		defer func() { r.opts.Report.Duration = report.Duration(time.Since(start)) }()
+	}
+
+	var failed failure
+
+	err = r.schedule(r.ctx, jobs, func(ctx context.Context, j job) error {
+		err := r.runTask(ctx, j, opts...)
+		failed.set(j.name, err)
+
+		t := r.opts.File.Tasks[j.task]
+		r.runHooks(ctx, hooks(err, t.OnSuccess, t.OnFailure), j.name, err, vars, opts)
+
+		return err
+	})
+
+	r.runHooks(r.ctx, hooks(err, r.opts.File.OnSuccess, r.opts.File.OnFailure), failed.task, failed.err, vars, opts)
+
+	return err
+}
+
+func (r *Runner) runTask(ctx context.Context, j job, opts ...spec.RunOpt) error {
+	t := r.opts.File.Tasks[j.task]
+
+	logger := r.opts.Logger.With(zap.String("task", j.name))
+	logger.Debug("running task")
+	start := time.Now()
+
+	if len(t.Status) > 0 && !r.opts.Dry {
+		ok, err := t.UpToDate(ctx, append(opts, spec.WithMatrix(j.matrix))...)
+		if err != nil {
+			j.report.Finish(report.StatusFailed, time.Since(start), err)
 			return err
 		}
+
+		if ok {
+			j.report.Finish(report.StatusUpToDate, time.Since(start), nil)
+			logger.Info("task is up to date", zap.Duration("duration", time.Since(start)))
+
+			return nil
+		}
+	}
+
+	var key string
+	// without sources, the outputs of a task do not depend on the files of the project
+	if r.opts.Cache != nil && len(t.Sources) > 0 && len(t.Generates) > 0 && !r.opts.Dry {
+		var err error
+		key, err = r.cacheKey(j)
+		if err != nil {
+			return err
+		}
+
+		ok, err := r.restore(ctx, key)
+		if err != nil {
+			logger.Warn("cannot restore task from cache", zap.String("key", key), zap.Error(err))
+		}
+
+		if ok {
+			j.report.Finish(report.StatusCached, time.Since(start), nil)
+			logger.Info("task restored from cache", zap.String("key", key), zap.Duration("duration", time.Since(start)))
+
+			return nil
+		}
+	}
+
+	stdout, stderr, closeOutput := r.opts.Output.Writers(j.name, r.Stdout(), r.Stderr())
+
+	err := t.Run(ctx, append(opts,
+		spec.WithMatrix(j.matrix),
+		spec.WithReport(j.report),
+		spec.WithLogger(logger),
+		spec.WithStdout(stdout),
+		spec.WithStderr(stderr),
+	)...)
+
+	if cerr := closeOutput(); cerr != nil && err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		j.report.Finish(report.StatusFailed, time.Since(start), err)
+		logger.Error("task failed", zap.Duration("duration", time.Since(start)), zap.Error(err))
+
+		return err
+	}
+
+	j.report.Finish(report.StatusSuccess, time.Since(start), nil)
+	logger.Info("task finished", zap.Duration("duration", time.Since(start)))
+
+	if key != "" {
+		if err := r.save(ctx, key, t.Generates); err != nil {
+			logger.Warn("cannot save task to cache", zap.String("key", key), zap.Error(err))
+		}
 	}
 
 	return nil
@@ -161,7 +480,7 @@ func WithContext(ctx context.Context, opts ...Opt) *Runner {
 	options := new(Opts)
 	options.Configure(opts...)
 
-	return &Runner{
+	r := &Runner{
 		ctx:  ctx,
 		opts: options,
 		pool: sync.Pool{
@@ -170,6 +489,15 @@ func WithContext(ctx context.Context, opts ...Opt) *Runner {
 			},
 		},
 	}
+
+	// the steps write to a pipe instead of the terminal if the output is masked,
+	// so it is only masked if there are secrets
+	if options.File != nil && len(options.File.Secrets) > 0 {
+		r.stdout = options.Masker.Writer(options.Stdout)
+		r.stderr = options.Masker.Writer(options.Stderr)
+	}
+
+	return r
 }
 
 // Use ...
@@ -0,0 +1,448 @@
+package runner_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/katallaxie/run/pkg/cache"
+	"github.com/katallaxie/run/pkg/report"
+	"github.com/katallaxie/run/pkg/runner"
+	"github.com/katallaxie/run/pkg/spec"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_RunTasks_Precedence(t *testing.T) {
+	type test struct {
+		name     string
+		process  bool
+		spec     bool
+		task     bool
+		step     bool
+		cli      bool
+		expected string
+	}
+
+	tests := []test{
+		{name: "process", process: true, expected: "process"},
+		{name: "spec over process", process: true, spec: true, expected: "spec"},
+		{name: "task over spec", process: true, spec: true, task: true, expected: "task"},
+		{name: "step over task", process: true, spec: true, task: true, step: true, expected: "step"},
+		{name: "cli over step", process: true, spec: true, task: true, step: true, cli: true, expected: "cli"},
+		{name: "cli over spec", spec: true, cli: true, expected: "cli"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			set := func(ok bool, v string) map[string]string {
+				if !ok {
+					return nil
+				}
+
+				return map[string]string{"FOO": v}
+			}
+
+			if tc.process {
+				t.Setenv("FOO", "process")
+			}
+
+			s := &spec.Spec{
+				Env:  set(tc.spec, "spec"),
+				Vars: set(tc.spec, "spec"),
+				Tasks: spec.Tasks{
+					"test": spec.Task{
+						Env:  set(tc.task, "task"),
+						Vars: set(tc.task, "task"),
+						Steps: spec.Steps{
+							{
+								Cmd:  `echo "$FOO {{.FOO}}"`,
+								Env:  set(tc.step, "step"),
+								Vars: set(tc.step, "step"),
+							},
+						},
+					},
+				},
+			}
+
+			var out bytes.Buffer
+			r := runner.WithContext(
+				context.Background(),
+				runner.WithSpec(s),
+				runner.WithStdout(&out),
+				runner.WithEnv(set(tc.cli, "cli")),
+				runner.WithVars(set(tc.cli, "cli")),
+			)
+
+			err := r.RunTasks("test")
+			assert.NoError(t, err)
+
+			// the process env is no var, so the action is kept
+			vars := tc.expected
+			if tc.expected == "process" {
+				vars = "{{.FOO}}"
+			}
+
+			assert.Equal(t, tc.expected+" "+vars+"\n", out.String())
+		})
+	}
+}
+
+func TestRunner_RunTasks_IsolatedSteps(t *testing.T) {
+	s := &spec.Spec{
+		Tasks: spec.Tasks{
+			"test": spec.Task{
+				Steps: spec.Steps{
+					{Cmd: `echo "$FOO {{.FOO}}"`, Env: spec.Env{"FOO": "first"}, Vars: spec.Vars{"FOO": "first"}},
+					{Cmd: `echo "$FOO {{.FOO}}"`},
+				},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	r := runner.WithContext(context.Background(), runner.WithSpec(s), runner.WithStdout(&out))
+
+	err := r.RunTasks("test")
+	assert.NoError(t, err)
+	assert.Equal(t, "first first\n {{.FOO}}\n", out.String())
+}
+
+func TestRunner_RunTasks_Matrix(t *testing.T) {
+	s := &spec.Spec{
+		Tasks: spec.Tasks{
+			"build": spec.Task{
+				Matrix: &spec.Matrix{
+					Axes: []spec.Axis{{Name: "goos", Values: []string{"linux", "darwin"}}},
+				},
+				Steps: spec.Steps{{Cmd: `echo "{{.goos}} $GOOS"`}},
+			},
+			"release": spec.Task{
+				DependsOn: spec.DependsOn{"build"},
+				Steps:     spec.Steps{{Cmd: `echo release`}},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	r := runner.WithContext(context.Background(), runner.WithSpec(s), runner.WithStdout(&out), runner.WithConcurrency(2))
+
+	tasks, err := s.Find("release")
+	assert.NoError(t, err)
+
+	err = r.RunTasks(tasks...)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "linux linux\n")
+	assert.Contains(t, out.String(), "darwin darwin\n")
+	assert.True(t, strings.HasSuffix(out.String(), "release\n"))
+}
+
+func TestRunner_RunTasks_Args(t *testing.T) {
+	s := &spec.Spec{
+		Tasks: spec.Tasks{
+			"test": spec.Task{
+				AcceptsArgs: true,
+				Steps:       spec.Steps{{Cmd: `echo {{.CLI_ARGS}}; echo "$RUN_CLI_ARGS"; echo "$# $2"`}},
+			},
+			"build": spec.Task{
+				Steps: spec.Steps{{Cmd: `echo build`}},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	r := runner.WithContext(context.Background(), runner.WithSpec(s), runner.WithStdout(&out), runner.WithArgs([]string{"-run", "Test Foo"}))
+
+	err := r.RunTasks("test")
+	assert.NoError(t, err)
+	assert.Equal(t, "-run Test Foo\n-run 'Test Foo'\n2 Test Foo\n", out.String())
+
+	err = r.RunTasks("build")
+	assert.ErrorIs(t, err, runner.ErrArgsNotAccepted)
+}
+
+func TestRunner_RunTasks_Cache(t *testing.T) {
+	dir := t.TempDir()
+
+	s := &spec.Spec{
+		Tasks: spec.Tasks{
+			"gen": spec.Task{
+				EnvFile:   spec.EnvFiles{{Path: ".env"}},
+				Sources:   []string{"in.txt"},
+				Generates: []string{"out/**"},
+				Steps:     spec.Steps{{Cmd: `echo generated; echo "{{.FOO}}" > out/gen.txt`}},
+			},
+		},
+	}
+
+	err := os.Mkdir(filepath.Join(dir, "out"), 0755)
+	assert.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(dir, "in.txt"), []byte("in"), 0644)
+	assert.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(dir, ".env"), []byte("BAR=a"), 0644)
+	assert.NoError(t, err)
+
+	store := cache.NewDir(t.TempDir())
+
+	run := func(foo string) (string, *report.Report) {
+		var out bytes.Buffer
+		rep := report.New()
+
+		r := runner.WithContext(
+			context.Background(),
+			runner.WithSpec(s),
+			runner.WithStdout(&out),
+			runner.WithWorkingDir(dir),
+			runner.WithVars(runner.Vars{"FOO": foo}),
+			runner.WithCache(store),
+			runner.WithReport(rep),
+		)
+
+		err := r.RunTasks("gen")
+		assert.NoError(t, err)
+
+		return out.String(), rep
+	}
+
+	out, rep := run("a")
+	assert.Equal(t, "generated\n", out)
+	assert.Equal(t, report.StatusSuccess, rep.Tasks[0].Status)
+
+	err = os.Remove(filepath.Join(dir, "out", "gen.txt"))
+	assert.NoError(t, err)
+
+	out, rep = run("a")
+	assert.Empty(t, out)
+	assert.Equal(t, report.StatusCached, rep.Tasks[0].Status)
+
+	b, err := os.ReadFile(filepath.Join(dir, "out", "gen.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "a\n", string(b))
+
+	out, _ = run("b")
+	assert.Equal(t, "generated\n", out)
+
+	err = os.WriteFile(filepath.Join(dir, ".env"), []byte("BAR=b"), 0644)
+	assert.NoError(t, err)
+
+	out, _ = run("b")
+	assert.Equal(t, "generated\n", out)
+
+	err = os.WriteFile(filepath.Join(dir, "in.txt"), []byte("changed"), 0644)
+	assert.NoError(t, err)
+
+	out, _ = run("b")
+	assert.Equal(t, "generated\n", out)
+
+	out, rep = run("b")
+	assert.Empty(t, out)
+	assert.Equal(t, report.StatusCached, rep.Tasks[0].Status)
+}
+
+func TestRunner_RunTasks_CacheWithoutSources(t *testing.T) {
+	dir := t.TempDir()
+
+	s := &spec.Spec{
+		Tasks: spec.Tasks{
+			"gen": spec.Task{
+				Generates: []string{"gen.txt"},
+				Steps:     spec.Steps{{Cmd: `echo generated; : > gen.txt`}},
+			},
+		},
+	}
+
+	cacheDir := t.TempDir()
+	store := cache.NewDir(cacheDir)
+
+	for i := 0; i < 2; i++ {
+		var out bytes.Buffer
+		rep := report.New()
+
+		r := runner.WithContext(
+			context.Background(),
+			runner.WithSpec(s),
+			runner.WithStdout(&out),
+			runner.WithWorkingDir(dir),
+			runner.WithCache(store),
+			runner.WithReport(rep),
+		)
+
+		err := r.RunTasks("gen")
+		assert.NoError(t, err)
+		assert.Equal(t, "generated\n", out.String())
+		assert.Equal(t, report.StatusSuccess, rep.Tasks[0].Status)
+	}
+
+	entries, err := os.ReadDir(cacheDir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRunner_RunTasks_EnvInherit(t *testing.T) {
+	t.Setenv("FOO", "process")
+	t.Setenv("BAR", "process")
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, ".env"), []byte("FILE=[${BAR}]"), 0644)
+	assert.NoError(t, err)
+
+	s := &spec.Spec{
+		EnvInherit: &spec.EnvInherit{},
+		EnvFile:    spec.EnvFiles{{Path: ".env"}},
+		Env:        spec.Env{"DECLARED": "spec"},
+		Tasks: spec.Tasks{
+			"test": spec.Task{
+				Steps: spec.Steps{
+					{Cmd: `echo "[$FOO] [$BAR] [$DECLARED] [${HOME:+home}] $FILE"`},
+					{Cmd: `echo "[$FOO] [$BAR]"`, EnvInherit: &spec.EnvInherit{Names: []string{"FOO"}}},
+				},
+			},
+			"all": spec.Task{
+				EnvInherit: &spec.EnvInherit{All: true},
+				EnvFile:    spec.EnvFiles{{Path: ".env"}},
+				Steps:      spec.Steps{{Cmd: `echo "[$FOO] [$BAR] $FILE"`}},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	r := runner.WithContext(context.Background(), runner.WithSpec(s), runner.WithStdout(&out), runner.WithWorkingDir(dir))
+
+	err = r.RunTasks("test", "all")
+	assert.NoError(t, err)
+	assert.Equal(t, "[] [] [spec] [home] []\n[process] []\n[process] [process] [process]\n", out.String())
+}
+
+func TestRunner_RunTasks_Hooks(t *testing.T) {
+	s := &spec.Spec{
+		OnFailure: []string{"notify"},
+		Tasks: spec.Tasks{
+			"test": spec.Task{
+				OnFailure: []string{"collect"},
+				Steps:     spec.Steps{{Id: "unit", Cmd: "exit 3"}},
+			},
+			"collect": spec.Task{
+				Steps: spec.Steps{{Cmd: `echo "collect {{.TASK_NAME}} {{.TASK_FAILED_STEP}} {{.TASK_ERROR}}"`}},
+			},
+			"notify": spec.Task{
+				Steps: spec.Steps{{Cmd: `echo "notify {{.TASK_NAME}} {{.TASK_OUTCOME}}"; exit 1`}},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	r := runner.WithContext(context.Background(), runner.WithSpec(s), runner.WithStdout(&out))
+
+	err := r.RunTasks("test")
+	assert.EqualError(t, err, "exit status 3")
+	assert.Equal(t, "collect test unit exit status 3\nnotify test failed\n", out.String())
+
+	s.Tasks["test"] = spec.Task{OnFailure: []string{"missing"}}
+	err = r.RunTasks("test")
+	assert.Error(t, err)
+}
+
+func TestRunner_RunTasks_HooksOfCanceledTasks(t *testing.T) {
+	s := &spec.Spec{
+		Tasks: spec.Tasks{
+			"fail": spec.Task{
+				Steps: spec.Steps{{Cmd: "exit 1"}},
+			},
+			"slow": spec.Task{
+				OnFailure: []string{"diagnose"},
+				Steps:     spec.Steps{{Cmd: "sleep 5"}},
+			},
+			"diagnose": spec.Task{
+				DependsOn: spec.DependsOn{"prepare"},
+				Steps:     spec.Steps{{Cmd: `echo "diagnose {{.TASK_NAME}} {{.TASK_OUTCOME}}"`}},
+			},
+			"prepare": spec.Task{
+				Steps: spec.Steps{{Cmd: "echo prepare"}},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	r := runner.WithContext(context.Background(), runner.WithSpec(s), runner.WithStdout(&out), runner.WithConcurrency(2))
+
+	err := r.RunTasks("slow", "fail")
+	assert.Error(t, err)
+	assert.Equal(t, "prepare\ndiagnose slow canceled\n", out.String())
+}
+
+func TestRunner_RunTasks_Status(t *testing.T) {
+	dir := t.TempDir()
+
+	s := &spec.Spec{
+		Tasks: spec.Tasks{
+			"build": spec.Task{
+				Status: []string{"test -f app", `[ "$(read -r v < app; echo $v)" = "{{.VERSION}}" ]`},
+				Steps:  spec.Steps{{Cmd: `echo built; echo "{{.VERSION}}" > app`}},
+			},
+		},
+	}
+
+	run := func(version string) (string, report.Status) {
+		var out bytes.Buffer
+		rep := report.New()
+
+		r := runner.WithContext(
+			context.Background(),
+			runner.WithSpec(s),
+			runner.WithStdout(&out),
+			runner.WithWorkingDir(dir),
+			runner.WithVars(runner.Vars{"VERSION": version}),
+			runner.WithReport(rep),
+		)
+
+		err := r.RunTasks("build")
+		assert.NoError(t, err)
+
+		return out.String(), rep.Tasks[0].Status
+	}
+
+	out, status := run("v1")
+	assert.Equal(t, "built\n", out)
+	assert.Equal(t, report.StatusSuccess, status)
+
+	out, status = run("v1")
+	assert.Empty(t, out)
+	assert.Equal(t, report.StatusUpToDate, status)
+
+	out, status = run("v2")
+	assert.Equal(t, "built\n", out)
+	assert.Equal(t, report.StatusSuccess, status)
+}
+
+func TestRunner_Stdout(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	r := runner.WithContext(context.Background(), runner.WithSpec(&spec.Spec{}), runner.WithStdout(f))
+	assert.Equal(t, f, r.Stdout())
+
+	s := &spec.Spec{
+		Secrets: spec.Secrets{"TOKEN": {Cmd: "echo s3cr3t"}},
+		Tasks: spec.Tasks{
+			"test": spec.Task{
+				Steps: spec.Steps{
+					{Cmd: "echo $TOKEN"},
+				},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	r = runner.WithContext(context.Background(), runner.WithSpec(s), runner.WithStdout(&out))
+	assert.NotEqual(t, &out, r.Stdout())
+
+	err = r.RunTasks("test")
+	assert.NoError(t, err)
+	assert.Equal(t, "***\n", out.String())
+}
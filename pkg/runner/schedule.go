@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/katallaxie/run/pkg/report"
+	"github.com/katallaxie/run/pkg/spec"
+)
+
+// job is a run of a task. A task with a matrix has a job for every combination.
+type job struct {
+	name   string
+	task   string
+	matrix spec.Vars
+	report *report.Task
+}
+
+type result struct {
+	job job
+	err error
+}
+
+// jobs returns the jobs of the tasks in the order of the list.
+func (r *Runner) jobs(tasks []string) []job {
+	jobs := make([]job, 0, len(tasks))
+
+	for _, task := range tasks {
+		m := r.opts.File.Tasks[task].Matrix
+		if m == nil {
+			jobs = append(jobs, job{name: task, task: task})
+			continue
+		}
+
+		for _, values := range m.Combinations() {
+			jobs = append(jobs, job{name: m.Name(task, values), task: task, matrix: values})
+		}
+	}
+
+	return jobs
+}
+
+// schedule runs the jobs with at most concurrency jobs at the same time.
+// A job is started when all jobs of the dependencies of its task have finished.
+// Ready jobs are started in the order of the list. No more jobs are started
+// after the first failure, and the context of the running jobs is canceled.
+func (r *Runner) schedule(ctx context.Context, jobs []job, run func(ctx context.Context, j job) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	remaining := make(map[string]int, len(jobs))
+	for _, j := range jobs {
+		remaining[j.task]++
+	}
+
+	ready := func(j job) bool {
+		for _, dep := range r.opts.File.Tasks[j.task].DependsOn {
+			if remaining[dep] > 0 {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	pending := append([]job(nil), jobs...)
+	results := make(chan result)
+	running := 0
+
+	var err error
+	for len(pending) > 0 || running > 0 {
+		for i := 0; err == nil && i < len(pending) && running < r.opts.Concurrency; {
+			j := pending[i]
+			if !ready(j) {
+				i++
+				continue
+			}
+
+			pending = append(pending[:i], pending[i+1:]...)
+			running++
+
+			go func() {
+				results <- result{job: j, err: run(ctx, j)}
+			}()
+		}
+
+		if running == 0 {
+			if err != nil {
+				break
+			}
+
+			names := make([]string, 0, len(pending))
+			for _, j := range pending {
+				names = append(names, j.name)
+			}
+
+			return fmt.Errorf("cannot schedule tasks: %s", strings.Join(names, ", "))
+		}
+
+		res := <-results
+		running--
+		remaining[res.job.task]--
+
+		if res.err != nil && err == nil {
+			err = res.err
+			cancel()
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,387 @@
+package scaffold
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/andersnormal/pkg/utils/files"
+)
+
+// DefaultMaxSize is the default limit of the size of an archive and of the files in it.
+const DefaultMaxSize = 1 << 30
+
+var (
+	// ErrUnsafePath signals an entry of an archive or a rendered file that is outside of the destination.
+	ErrUnsafePath = errors.New("path is outside of the destination")
+	// ErrTooLarge signals an archive or files that exceed the size limit.
+	ErrTooLarge = errors.New("archive exceeds the size limit")
+	// ErrChecksum signals an archive that does not match the checksum.
+	ErrChecksum = errors.New("checksum mismatch")
+	// ErrUnknownFormat signals an archive that is not a zip, tar or gzip compressed tar archive.
+	ErrUnknownFormat = errors.New("unknown archive format")
+)
+
+// archiveExts are the extensions of the archives that are detected by NewProvider.
+var archiveExts = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+type archiveProvider struct {
+	opts *ProviderOpts
+}
+
+// NewArchive ...
+func NewArchive(opts ...ProviderOpt) Provider {
+	options := new(ProviderOpts)
+	options.Configure(opts...)
+
+	if options.MaxSize <= 0 {
+		options.MaxSize = DefaultMaxSize
+	}
+
+	p := new(archiveProvider)
+	p.opts = options
+
+	return p
+}
+
+// CloneWithContext downloads the zip, tar or gzip compressed tar archive at url
+// and extracts it to the folder. The url can be a HTTP(S) or file:// URL, or a path.
+// The archive is verified with the checksum before it is extracted. Entries that are
+// not regular files or directories, like symlinks, are skipped.
+func (a *archiveProvider) CloneWithContext(ctx context.Context, url, folder string) error {
+	root, err := filepath.Abs(folder)
+	if err != nil {
+		return err
+	}
+
+	empty, err := files.IsDirEmpty(root)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if !os.IsNotExist(err) && !empty {
+		return ErrFolderNotEmpty
+	}
+
+	if a.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.opts.Timeout)
+		defer cancel()
+	}
+
+	x := &extractor{
+		ctx:       ctx,
+		root:      root,
+		strip:     a.opts.StripComponents,
+		remaining: a.opts.MaxSize,
+	}
+
+	if a.opts.Subdir != "" {
+		x.subdir = path.Clean(filepath.ToSlash(a.opts.Subdir))
+		if !safe(x.subdir) {
+			return fmt.Errorf("%w: %s", ErrUnsafePath, a.opts.Subdir)
+		}
+	}
+
+	f, size, err := a.fetch(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	magic := make([]byte, 262)
+	n, err := f.ReadAt(magic, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	magic = magic[:n]
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")), bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		return x.zip(f, size)
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+
+		return x.tar(zr)
+	case len(magic) == 262 && string(magic[257:262]) == "ustar":
+		return x.tar(f)
+	default:
+		return ErrUnknownFormat
+	}
+}
+
+// fetch writes the archive to a temporary file and verifies its size and checksum.
+func (a *archiveProvider) fetch(ctx context.Context, src string) (*os.File, int64, error) {
+	var h hash.Hash
+	var sum string
+	if a.opts.Checksum != "" {
+		var err error
+		if h, sum, err = checksum(a.opts.Checksum); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	r, err := open(ctx, src)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer r.Close()
+
+	f, err := ioutil.TempFile("", "run-archive-")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cleanup := func(err error) (*os.File, int64, error) {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, 0, err
+	}
+
+	var w io.Writer = f
+	if h != nil {
+		w = io.MultiWriter(f, h)
+	}
+
+	n, err := io.Copy(w, io.LimitReader(r, a.opts.MaxSize+1))
+	if err != nil {
+		return cleanup(err)
+	}
+
+	if n > a.opts.MaxSize {
+		return cleanup(ErrTooLarge)
+	}
+
+	if h != nil && hex.EncodeToString(h.Sum(nil)) != sum {
+		return cleanup(fmt.Errorf("%w: %s", ErrChecksum, src))
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return cleanup(err)
+	}
+
+	return f, n, nil
+}
+
+// open opens a local file or the body of the response to a GET request.
+func open(ctx context.Context, src string) (io.ReadCloser, error) {
+	u, err := url.Parse(src)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+		return os.Open(src)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return os.Open(filepath.FromSlash(u.Path))
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported scheme: %s", u.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", src, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// checksum returns the hash and the hex encoded sum of the checksum,
+// e.g. sha256:<hex>. A checksum without algorithm is a SHA-256 sum.
+func checksum(s string) (hash.Hash, string, error) {
+	algo, sum, ok := strings.Cut(s, ":")
+	if !ok {
+		algo, sum = "sha256", s
+	}
+
+	switch strings.ToLower(algo) {
+	case "sha256":
+		return sha256.New(), strings.ToLower(sum), nil
+	case "sha512":
+		return sha512.New(), strings.ToLower(sum), nil
+	default:
+		return nil, "", fmt.Errorf("unsupported checksum algorithm: %s", algo)
+	}
+}
+
+// isArchive reports if the path of the source has the extension of an archive.
+func isArchive(src string) bool {
+	p := src
+	if u, err := url.Parse(src); err == nil && u.Path != "" {
+		p = u.Path
+	}
+
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(p, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type extractor struct {
+	ctx       context.Context
+	root      string
+	strip     int
+	subdir    string
+	remaining int64
+}
+
+// path returns the destination of an entry, or an empty string if the entry
+// is not extracted because of the stripped components or the subdir.
+func (x *extractor) path(name string) (string, error) {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if !safe(name) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+
+	parts := strings.Split(path.Clean(name), "/")
+	if len(parts) <= x.strip {
+		return "", nil
+	}
+	rel := strings.Join(parts[x.strip:], "/")
+	if rel == "." {
+		return "", nil
+	}
+
+	if x.subdir != "" && x.subdir != "." {
+		if !strings.HasPrefix(rel, x.subdir+"/") {
+			return "", nil
+		}
+		rel = strings.TrimPrefix(rel, x.subdir+"/")
+	}
+
+	dst := filepath.Join(x.root, filepath.FromSlash(rel))
+	if !strings.HasPrefix(dst, x.root+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+
+	return dst, nil
+}
+
+// safe reports if the name is relative and does not leave its root.
+func safe(name string) bool {
+	if path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return false
+	}
+
+	c := path.Clean(name)
+
+	return c != ".." && !strings.HasPrefix(c, "../")
+}
+
+func (x *extractor) entry(name string, mode fs.FileMode, r func() (io.ReadCloser, error)) error {
+	if err := x.ctx.Err(); err != nil {
+		return err
+	}
+
+	dst, err := x.path(name)
+	if err != nil || dst == "" {
+		return err
+	}
+
+	switch {
+	case mode.IsDir():
+		return os.MkdirAll(dst, os.ModePerm)
+	case mode.IsRegular():
+	default:
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+
+	rc, err := r()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm()|0o600)
+	if err != nil {
+		return err
+	}
+
+	n, err := io.Copy(out, io.LimitReader(rc, x.remaining+1))
+	x.remaining -= n
+
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil && x.remaining < 0 {
+		err = ErrTooLarge
+	}
+
+	return err
+}
+
+func (x *extractor) tar(r io.Reader) error {
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = x.entry(hdr.Name, hdr.FileInfo().Mode(), func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(tr), nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (x *extractor) zip(r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		if err := x.entry(f.Name, f.Mode(), f.Open); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
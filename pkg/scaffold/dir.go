@@ -0,0 +1,109 @@
+package scaffold
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andersnormal/pkg/utils/files"
+)
+
+type dir struct {
+	opts *ProviderOpts
+}
+
+// NewDir returns a provider that copies a local directory.
+func NewDir(opts ...ProviderOpt) Provider {
+	options := new(ProviderOpts)
+	options.Configure(opts...)
+
+	d := new(dir)
+	d.opts = options
+
+	return d
+}
+
+// CloneWithContext copies the files of the directory at url, or of the subdir
+// in it, to the folder. The .git directory is not copied.
+func (d *dir) CloneWithContext(ctx context.Context, url string, folder string) error {
+	path, err := filepath.Abs(folder)
+	if err != nil {
+		return err
+	}
+
+	empty, err := files.IsDirEmpty(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if !os.IsNotExist(err) && !empty {
+		return ErrFolderNotEmpty
+	}
+
+	if d.opts.Subdir != "" {
+		dir := filepath.Clean(d.opts.Subdir)
+		if filepath.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("subdir %s is outside of %s", d.opts.Subdir, url)
+		}
+		url = filepath.Join(url, dir)
+	}
+
+	return filepath.WalkDir(url, func(src string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if e.IsDir() && e.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(url, src)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(path, rel)
+
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+
+		if e.IsDir() {
+			return os.MkdirAll(dst, os.ModePerm)
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		return copyFile(src, dst, info.Mode())
+	})
+}
+
+func copyFile(src, dst string, mode fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,187 @@
+package scaffold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/andersnormal/pkg/utils/files"
+	gg "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+var (
+	// ErrFolderNotEmpty signals that the destination folder not empty
+	ErrFolderNotEmpty = errors.New("destination folder not empty")
+)
+
+type git struct {
+	opts *ProviderOpts
+}
+
+// NewGit ...
+func NewGit(opts ...ProviderOpt) Provider {
+	options := new(ProviderOpts)
+
+	g := new(git)
+	g.opts = options
+	g.opts.Configure(opts...)
+
+	return g
+}
+
+// CloneWithContext copies the files of the ref, or of HEAD, of the repository
+// at url to the folder. Only the files in the subdir are copied if it is set.
+func (g *git) CloneWithContext(ctx context.Context, url string, folder string) error {
+	path, err := filepath.Abs(folder)
+	if err != nil {
+		return err
+	}
+
+	empty, err := files.IsDirEmpty(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if !os.IsNotExist(err) && !empty {
+		return ErrFolderNotEmpty
+	}
+
+	commit, err := g.commit(ctx, url)
+	if err != nil {
+		return err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+
+	if g.opts.Subdir != "" {
+		tree, err = subtree(tree, g.opts.Subdir)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tree.Files().ForEach(func(f *object.File) error {
+		fpath := filepath.Join(path, filepath.FromSlash(f.Name))
+
+		if err = os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
+			return err
+		}
+
+		mode, err := f.Mode.ToOSFileMode()
+		if err != nil {
+			return err
+		}
+
+		// submodules and symlinks are not part of a scaffold
+		if !mode.IsRegular() {
+			return nil
+		}
+
+		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+		if err != nil {
+			return err
+		}
+		defer outFile.Close()
+
+		r, err := f.Reader()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		_, err = io.Copy(outFile, r)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// commit clones the repository and returns the commit of the ref, or of HEAD.
+// Branches and tags are cloned without history, other revisions need the full history.
+func (g *git) commit(ctx context.Context, url string) (*object.Commit, error) {
+	if g.opts.Ref == "" {
+		r, err := gg.CloneContext(ctx, memory.NewStorage(), nil, &gg.CloneOptions{
+			URL:   url,
+			Depth: 1,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		head, err := r.Head()
+		if err != nil {
+			return nil, err
+		}
+
+		return r.CommitObject(head.Hash())
+	}
+
+	for _, name := range []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName(g.opts.Ref),
+		plumbing.NewTagReferenceName(g.opts.Ref),
+	} {
+		r, err := gg.CloneContext(ctx, memory.NewStorage(), nil, &gg.CloneOptions{
+			URL:           url,
+			ReferenceName: name,
+			SingleBranch:  true,
+			Depth:         1,
+			Tags:          gg.NoTags,
+		})
+		if errors.Is(err, gg.NoMatchingRefSpecError{}) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return resolve(r, string(name))
+	}
+
+	r, err := gg.CloneContext(ctx, memory.NewStorage(), nil, &gg.CloneOptions{
+		URL: url,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resolve(r, g.opts.Ref)
+}
+
+func resolve(r *gg.Repository, rev string) (*object.Commit, error) {
+	hash, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve %s: %w", rev, err)
+	}
+
+	return r.CommitObject(*hash)
+}
+
+func subtree(tree *object.Tree, dir string) (*object.Tree, error) {
+	dir = path.Clean(filepath.ToSlash(dir))
+	if dir == "." {
+		return tree, nil
+	}
+
+	if path.IsAbs(dir) || dir == ".." || len(dir) > 2 && dir[:3] == "../" {
+		return nil, fmt.Errorf("subdir %s is outside of the repository", dir)
+	}
+
+	t, err := tree.Tree(dir)
+	if err != nil {
+		return nil, fmt.Errorf("subdir %s: %w", dir, err)
+	}
+
+	return t, nil
+}
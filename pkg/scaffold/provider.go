@@ -0,0 +1,111 @@
+package scaffold
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ProviderOpt ...
+type ProviderOpt func(*ProviderOpts)
+
+// Opts ...
+type ProviderOpts struct {
+	Timeout time.Duration
+	Logger  *zap.Logger
+	URL     string
+	// Ref is the branch, tag or commit of a git repository.
+	Ref string
+	// Subdir is the directory of the scaffold in the source.
+	Subdir string
+	// StripComponents is the number of leading directories that are removed from the entries of an archive.
+	StripComponents int
+	// MaxSize is the limit of the size of an archive and of the files in it.
+	MaxSize int64
+	// Checksum is the checksum of an archive, e.g. sha256:<hex>.
+	Checksum string
+}
+
+// Configure os configuring the options.
+func (o *ProviderOpts) Configure(opts ...ProviderOpt) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithRef sets the branch, tag or commit that is cloned.
+func WithRef(ref string) ProviderOpt {
+	return func(o *ProviderOpts) {
+		o.Ref = ref
+	}
+}
+
+// WithSubdir sets the directory in the source that is copied.
+func WithSubdir(dir string) ProviderOpt {
+	return func(o *ProviderOpts) {
+		o.Subdir = dir
+	}
+}
+
+// WithStripComponents sets the number of leading directories that are removed
+// from the entries of an archive.
+func WithStripComponents(n int) ProviderOpt {
+	return func(o *ProviderOpts) {
+		o.StripComponents = n
+	}
+}
+
+// WithMaxSize sets the limit of the size of an archive and of the files in it.
+func WithMaxSize(n int64) ProviderOpt {
+	return func(o *ProviderOpts) {
+		o.MaxSize = n
+	}
+}
+
+// WithChecksum sets the checksum an archive is verified with, e.g. sha256:<hex>.
+func WithChecksum(sum string) ProviderOpt {
+	return func(o *ProviderOpts) {
+		o.Checksum = sum
+	}
+}
+
+// Provider ...
+type Provider interface {
+	// CloneWithContext ...
+	CloneWithContext(ctx context.Context, url string, folder string) error
+}
+
+// NewProvider returns the provider for the source of a scaffold.
+// A local directory is copied, a zip, tar or tar.gz archive is extracted
+// and everything else is cloned with git.
+func NewProvider(src string, opts ...ProviderOpt) Provider {
+	if fi, err := os.Stat(src); err == nil && fi.IsDir() {
+		return NewDir(opts...)
+	}
+
+	if isArchive(src) {
+		return NewArchive(opts...)
+	}
+
+	return NewGit(opts...)
+}
+
+// NewProviderByName returns the provider with the name, or the provider
+// for the source if the name is empty.
+func NewProviderByName(name, src string, opts ...ProviderOpt) (Provider, error) {
+	switch name {
+	case "":
+		return NewProvider(src, opts...), nil
+	case "git":
+		return NewGit(opts...), nil
+	case "archive":
+		return NewArchive(opts...), nil
+	case "dir":
+		return NewDir(opts...), nil
+	default:
+		return nil, fmt.Errorf("unknown provider: %s", name)
+	}
+}
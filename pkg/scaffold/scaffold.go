@@ -0,0 +1,300 @@
+package scaffold
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/katallaxie/run/pkg/cache"
+	"github.com/katallaxie/run/pkg/tmpl"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile is the file that declares the inputs of a scaffold.
+const ManifestFile = ".sc.yaml"
+
+// TemplateExt is the extension of the files that are rendered.
+// It is removed from the name of the rendered file.
+const TemplateExt = ".tmpl"
+
+// ErrFileExists signals that a file of the scaffold already exists in the destination.
+var ErrFileExists = errors.New("file already exists")
+
+// Manifest ...
+type Manifest struct {
+	// Name ...
+	Name string `yaml:"name,omitempty"`
+	// Description ...
+	Description string `yaml:"description,omitempty"`
+	// Inputs are the values that are asked for before the files are rendered.
+	Inputs []Input `yaml:"inputs,omitempty"`
+	// Ignore are the glob patterns of the files that are not rendered.
+	Ignore []string `yaml:"ignore,omitempty"`
+}
+
+// FileError is an error of a file of the scaffold.
+type FileError struct {
+	// Path is the path of the file in the destination.
+	Path string
+	Err  error
+}
+
+// Error ...
+func (e *FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Unwrap ...
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// RenderOpt ...
+type RenderOpt func(*RenderOpts)
+
+// RenderOpts ...
+type RenderOpts struct {
+	Force  bool
+	Ignore []string
+}
+
+// Configure ...
+func (o *RenderOpts) Configure(opts ...RenderOpt) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithForce overwrites existing files.
+func WithForce(force bool) RenderOpt {
+	return func(o *RenderOpts) {
+		o.Force = force
+	}
+}
+
+// WithIgnore adds glob patterns of the files that are not rendered.
+// In addition to the syntax of path.Match, `**` matches any number of directories.
+func WithIgnore(patterns ...string) RenderOpt {
+	return func(o *RenderOpts) {
+		o.Ignore = append(o.Ignore, patterns...)
+	}
+}
+
+// Input ...
+type Input struct {
+	// Name is the name of the field in the templates.
+	Name string `yaml:"name"`
+	// Desc is shown when the value is asked for.
+	Desc string `yaml:"desc,omitempty"`
+	// Default is used if no value is given.
+	Default string `yaml:"default,omitempty"`
+	// Required inputs must have a value.
+	Required bool `yaml:"required,omitempty"`
+}
+
+// Load reads the manifest in the directory of a scaffold.
+// A scaffold without a manifest has no inputs.
+func Load(dir string) (*Manifest, error) {
+	m := new(Manifest)
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, ManifestFile))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("%s: %w", ManifestFile, err)
+	}
+
+	return m, nil
+}
+
+// Fields returns the values of the inputs. The values are taken from vars,
+// else asked for on w and read from r if r is not nil, else the default.
+func (m *Manifest) Fields(vars map[string]string, r io.Reader, w io.Writer) (tmpl.Fields, error) {
+	fields := make(tmpl.Fields, len(m.Inputs))
+
+	var scanner *bufio.Scanner
+	if r != nil {
+		scanner = bufio.NewScanner(r)
+	}
+
+	for _, in := range m.Inputs {
+		v, ok := vars[in.Name]
+
+		for !ok && scanner != nil {
+			prompt := in.Name
+			if in.Desc != "" {
+				prompt = in.Desc
+			}
+			if in.Default != "" {
+				prompt += fmt.Sprintf(" [%s]", in.Default)
+			}
+			fmt.Fprintf(w, "%s: ", prompt)
+
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return nil, err
+				}
+				break
+			}
+
+			v = strings.TrimSpace(scanner.Text())
+			if v == "" {
+				v = in.Default
+			}
+			ok = v != "" || !in.Required
+		}
+
+		if !ok {
+			v = in.Default
+		}
+
+		if v == "" && in.Required {
+			return nil, fmt.Errorf("missing value of input %s, use --var %s=...", in.Name, in.Name)
+		}
+
+		fields[in.Name] = v
+	}
+
+	return fields, nil
+}
+
+// Render writes the files of the scaffold in src to dst. The names of the
+// files and the files with the TemplateExt are rendered with the fields.
+// Existing files are only overwritten with WithForce.
+func Render(src, dst string, fields tmpl.Fields, opts ...RenderOpt) error {
+	options := new(RenderOpts)
+	options.Configure(opts...)
+
+	t := tmpl.New(tmpl.WithExtraFields(tmpl.TmplFields(fields)), tmpl.WithFailOnMissing())
+
+	ignored := make(map[string]bool)
+	if len(options.Ignore) > 0 {
+		ff, err := cache.Glob(src, options.Ignore)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range ff {
+			ignored[f] = true
+		}
+	}
+
+	type file struct {
+		name, src, dst string
+		mode           fs.FileMode
+		render         bool
+	}
+	var ff []file
+
+	err := filepath.WalkDir(src, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if e.IsDir() {
+			if e.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		if rel == ManifestFile || ignored[filepath.ToSlash(rel)] {
+			return nil
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		name, err := t.Apply(rel)
+		if err != nil {
+			return &FileError{Path: rel, Err: err}
+		}
+
+		render := strings.HasSuffix(name, TemplateExt)
+		name = strings.TrimSuffix(name, TemplateExt)
+
+		// the names are rendered with the inputs, e.g. `{{.name}}.txt` with `../evil`
+		if !inside(dst, name) {
+			return &FileError{Path: name, Err: ErrUnsafePath}
+		}
+
+		if !options.Force {
+			if _, err := os.Stat(filepath.Join(dst, name)); err == nil {
+				return &FileError{Path: name, Err: ErrFileExists}
+			}
+		}
+
+		ff = append(ff, file{name: name, src: path, dst: filepath.Join(dst, name), mode: info.Mode(), render: render})
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, f := range ff {
+		if err := os.MkdirAll(filepath.Dir(f.dst), os.ModePerm); err != nil {
+			return err
+		}
+
+		if !f.render {
+			if err := copyFile(f.src, f.dst, f.mode); err != nil {
+				return err
+			}
+			continue
+		}
+
+		b, err := ioutil.ReadFile(f.src)
+		if err != nil {
+			return err
+		}
+
+		s, err := t.Apply(string(b))
+		if err != nil {
+			return &FileError{Path: f.name, Err: err}
+		}
+
+		if err := ioutil.WriteFile(f.dst, []byte(s), f.mode.Perm()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// inside reports if the relative name is a file in the dir.
+func inside(dir, name string) bool {
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return false
+	}
+
+	rel, err := filepath.Rel(dir, filepath.Join(dir, name))
+	if err != nil {
+		return false
+	}
+
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,371 @@
+package scaffold_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katallaxie/run/pkg/cache"
+	"github.com/katallaxie/run/pkg/scaffold"
+	"github.com/katallaxie/run/pkg/spec"
+	"github.com/katallaxie/run/pkg/tmpl"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func writeFiles(t *testing.T, dir string, ff map[string]string) {
+	for name, content := range ff {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestManifest_Fields(t *testing.T) {
+	m := &scaffold.Manifest{Inputs: []scaffold.Input{
+		{Name: "module", Desc: "Go module", Required: true},
+		{Name: "go", Default: "1.21"},
+		{Name: "license", Default: "MIT"},
+	}}
+
+	fields, err := m.Fields(map[string]string{"license": "Apache-2.0"}, strings.NewReader("\nexample.com/app\n\n"), &bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.Equal(t, tmpl.Fields{"module": "example.com/app", "go": "1.21", "license": "Apache-2.0"}, fields)
+
+	_, err = m.Fields(nil, nil, nil)
+	assert.EqualError(t, err, "missing value of input module, use --var module=...")
+
+	fields, err = m.Fields(map[string]string{"module": "m"}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.21", fields["go"])
+}
+
+func TestRender(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	writeFiles(t, src, map[string]string{
+		scaffold.ManifestFile: "inputs: [{name: name}]",
+		"go.mod.tmpl":         "module {{.name}}\n",
+		"{{.name}}/main.go":   "package main // {{.name}}\n",
+		".git/config":         "",
+		"docs/README.md.tmpl": "# {{.name}}\n",
+	})
+
+	err := scaffold.Render(src, dst, tmpl.Fields{"name": "app"})
+	assert.NoError(t, err)
+
+	for name, want := range map[string]string{
+		"go.mod":         "module app\n",
+		"app/main.go":    "package main // {{.name}}\n",
+		"docs/README.md": "# app\n",
+	} {
+		b, err := os.ReadFile(filepath.Join(dst, name))
+		assert.NoError(t, err)
+		assert.Equal(t, want, string(b))
+	}
+
+	assert.NoFileExists(t, filepath.Join(dst, scaffold.ManifestFile))
+	assert.NoDirExists(t, filepath.Join(dst, ".git"))
+
+	err = scaffold.Render(src, dst, tmpl.Fields{"name": "app"})
+	assert.ErrorIs(t, err, scaffold.ErrFileExists)
+
+	var fileErr *scaffold.FileError
+	assert.ErrorAs(t, err, &fileErr)
+	assert.NoError(t, scaffold.Render(src, dst, tmpl.Fields{"name": "app"}, scaffold.WithForce(true)))
+
+	writeFiles(t, src, map[string]string{"missing.tmpl": "{{.missing}}"})
+	assert.Error(t, scaffold.Render(src, t.TempDir(), tmpl.Fields{"name": "app"}))
+}
+
+func TestRender_UnsafePath(t *testing.T) {
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{"{{.name}}.txt": "evil", "{{.dir}}/{{.file}}": "evil"})
+
+	tests := []struct {
+		name   string
+		fields tmpl.Fields
+		path   string
+	}{
+		{name: "parent", fields: tmpl.Fields{"name": "../evil", "dir": "a", "file": "b"}, path: "../evil.txt"},
+		{name: "nested parent", fields: tmpl.Fields{"name": "a", "dir": "a/../..", "file": "evil"}, path: "a/../../evil"},
+		{name: "absolute", fields: tmpl.Fields{"name": "a", "dir": "", "file": "evil"}, path: "/evil"},
+		{name: "destination", fields: tmpl.Fields{"name": "a", "dir": ".", "file": ""}, path: "./"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			dst := filepath.Join(root, "out", "project")
+
+			err := scaffold.Render(src, dst, tc.fields)
+			assert.ErrorIs(t, err, scaffold.ErrUnsafePath)
+
+			var fileErr *scaffold.FileError
+			if assert.ErrorAs(t, err, &fileErr) {
+				assert.Equal(t, tc.path, fileErr.Path)
+			}
+
+			// nothing is written if a file is outside of the destination
+			ff, err := cache.Glob(root, []string{"**"})
+			assert.NoError(t, err)
+			assert.Empty(t, ff)
+		})
+	}
+}
+
+func TestNewDir(t *testing.T) {
+	src, dst := t.TempDir(), filepath.Join(t.TempDir(), "out")
+	writeFiles(t, src, map[string]string{"a/b.txt": "b", ".git/HEAD": "ref"})
+
+	err := scaffold.NewDir().CloneWithContext(context.Background(), src, dst)
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dst, "a/b.txt"))
+	assert.NoDirExists(t, filepath.Join(dst, ".git"))
+
+	err = scaffold.NewDir().CloneWithContext(context.Background(), src, dst)
+	assert.ErrorIs(t, err, scaffold.ErrFolderNotEmpty)
+}
+
+func TestStarter(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"go.mod": "", "package.json": "{}", "Dockerfile": ""})
+
+	types := scaffold.Detect(dir)
+	assert.Equal(t, []scaffold.Type{scaffold.Go, scaffold.Node, scaffold.Docker}, types)
+
+	var s spec.Spec
+	err := yaml.Unmarshal(scaffold.Starter("app", types...), &s)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, s.Spec)
+	assert.Equal(t, []string{"build"}, s.Default())
+	assert.Equal(t, "app", s.Vars["image"])
+	assert.Equal(t, []string{"build", "docker-build", "install", "lint", "node-build", "node-test", "test"}, s.Names())
+
+	_, err = s.Find("node-build")
+	assert.NoError(t, err)
+
+	err = yaml.Unmarshal(scaffold.Starter("app"), &s)
+	assert.NoError(t, err)
+}
+
+func TestNewGit(t *testing.T) {
+	dir := t.TempDir()
+
+	r, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+
+	sig := &object.Signature{Name: "run", Email: "run@example.com", When: time.Now()}
+	commit := func(ff map[string]string) plumbing.Hash {
+		writeFiles(t, dir, ff)
+		require.NoError(t, wt.AddGlob("."))
+		h, err := wt.Commit("commit", &git.CommitOptions{Author: sig})
+		require.NoError(t, err)
+		return h
+	}
+
+	first := commit(map[string]string{"tmpl/README.md": "one", "root.txt": ""})
+	_, err = r.CreateTag("v1", first, &git.CreateTagOptions{Tagger: sig, Message: "v1"})
+	require.NoError(t, err)
+	_, err = r.CreateTag("light", first, nil)
+	require.NoError(t, err)
+	require.NoError(t, r.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("feature"), first)))
+
+	commit(map[string]string{"tmpl/README.md": "two"})
+
+	tests := []struct {
+		ref    string
+		subdir string
+		file   string
+		want   string
+		err    bool
+	}{
+		{file: "tmpl/README.md", want: "two"},
+		{ref: "v1", file: "tmpl/README.md", want: "one"},
+		{ref: "light", file: "tmpl/README.md", want: "one"},
+		{ref: "feature", file: "tmpl/README.md", want: "one"},
+		{ref: first.String(), file: "tmpl/README.md", want: "one"},
+		{ref: "v1", subdir: "tmpl", file: "README.md", want: "one"},
+		{ref: "unknown", err: true},
+		{subdir: "../tmpl", err: true},
+		{subdir: "missing", err: true},
+	}
+
+	for _, tc := range tests {
+		out := t.TempDir()
+		p := scaffold.NewGit(scaffold.WithRef(tc.ref), scaffold.WithSubdir(tc.subdir))
+
+		err := p.CloneWithContext(context.Background(), "file://"+dir, out)
+		if tc.err {
+			assert.Error(t, err, tc.ref+tc.subdir)
+			continue
+		}
+		require.NoError(t, err, tc.ref+tc.subdir)
+
+		b, err := os.ReadFile(filepath.Join(out, tc.file))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, string(b), tc.ref+tc.subdir)
+	}
+}
+
+func TestRender_Ignore(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	writeFiles(t, src, map[string]string{"a.md": "", "docs/b.md": "", "docs/c.txt": "", "main.go": ""})
+
+	err := scaffold.Render(src, dst, nil, scaffold.WithIgnore("*.md", "docs/**/*.txt"))
+	assert.NoError(t, err)
+
+	ff, err := cache.Glob(dst, []string{"**"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docs/b.md", "main.go"}, ff)
+}
+
+type entry struct {
+	name    string
+	content string
+	link    bool
+}
+
+func tarball(t *testing.T, gz bool, entries ...entry) []byte {
+	var buf bytes.Buffer
+
+	var w io.Writer = &buf
+	var zw *gzip.Writer
+	if gz {
+		zw = gzip.NewWriter(&buf)
+		w = zw
+	}
+
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+		if e.link {
+			hdr = &tar.Header{Name: e.name, Linkname: e.content, Typeflag: tar.TypeSymlink}
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		if !e.link {
+			_, err := tw.Write([]byte(e.content))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+
+	if zw != nil {
+		require.NoError(t, zw.Close())
+	}
+
+	return buf.Bytes()
+}
+
+func zipball(t *testing.T, entries ...entry) []byte {
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+func TestNewArchive(t *testing.T) {
+	files := []entry{{name: "app-1.0/README.md", content: "readme"}, {name: "app-1.0/tmpl/go.mod", content: "module"}}
+
+	tests := []struct {
+		name    string
+		archive []byte
+		opts    []scaffold.ProviderOpt
+		want    map[string]string
+		err     error
+	}{
+		{name: "tar", archive: tarball(t, false, files...), want: map[string]string{"app-1.0/README.md": "readme", "app-1.0/tmpl/go.mod": "module"}},
+		{name: "tar.gz", archive: tarball(t, true, files...), opts: []scaffold.ProviderOpt{scaffold.WithStripComponents(1)}, want: map[string]string{"README.md": "readme", "tmpl/go.mod": "module"}},
+		{name: "zip", archive: zipball(t, files...), opts: []scaffold.ProviderOpt{scaffold.WithStripComponents(1), scaffold.WithSubdir("tmpl")}, want: map[string]string{"go.mod": "module"}},
+		{name: "zip slip", archive: zipball(t, entry{name: "../evil", content: "x"}), err: scaffold.ErrUnsafePath},
+		{name: "tar slip", archive: tarball(t, false, entry{name: "a/../../evil", content: "x"}), err: scaffold.ErrUnsafePath},
+		{name: "absolute", archive: tarball(t, true, entry{name: "/etc/evil", content: "x"}), err: scaffold.ErrUnsafePath},
+		{name: "symlink", archive: tarball(t, false, entry{name: "link", content: "/etc/passwd", link: true}, entry{name: "a", content: "a"}), want: map[string]string{"a": "a"}},
+		{name: "max size", archive: tarball(t, false, entry{name: "a", content: strings.Repeat("a", 4096)}), opts: []scaffold.ProviderOpt{scaffold.WithMaxSize(5000)}, err: scaffold.ErrTooLarge},
+		{name: "download size", archive: zipball(t, files...), opts: []scaffold.ProviderOpt{scaffold.WithMaxSize(10)}, err: scaffold.ErrTooLarge},
+		{name: "unknown", archive: []byte("no archive"), err: scaffold.ErrUnknownFormat},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			src := filepath.Join(t.TempDir(), "archive")
+			require.NoError(t, os.WriteFile(src, tc.archive, 0o644))
+
+			dst := filepath.Join(t.TempDir(), "out")
+			err := scaffold.NewArchive(tc.opts...).CloneWithContext(context.Background(), "file://"+src, dst)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				assert.NoFileExists(t, filepath.Join(filepath.Dir(dst), "evil"))
+				return
+			}
+			require.NoError(t, err)
+
+			for name, want := range tc.want {
+				b, err := os.ReadFile(filepath.Join(dst, name))
+				assert.NoError(t, err)
+				assert.Equal(t, want, string(b))
+			}
+
+			ff, err := cache.Glob(dst, []string{"**"})
+			assert.NoError(t, err)
+			assert.Len(t, ff, len(tc.want))
+		})
+	}
+}
+
+func TestNewArchive_HTTP(t *testing.T) {
+	archive := tarball(t, true, entry{name: "a.txt", content: "a"})
+	sum := sha256.Sum256(archive)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/app.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(archive)
+	}))
+	defer srv.Close()
+
+	p := scaffold.NewProvider(srv.URL+"/app.tar.gz", scaffold.WithChecksum("sha256:"+hex.EncodeToString(sum[:])))
+	dst := t.TempDir()
+	assert.NoError(t, p.CloneWithContext(context.Background(), srv.URL+"/app.tar.gz", dst))
+	assert.FileExists(t, filepath.Join(dst, "a.txt"))
+
+	p = scaffold.NewArchive(scaffold.WithChecksum(strings.Repeat("0", 64)))
+	err := p.CloneWithContext(context.Background(), srv.URL+"/app.tar.gz", t.TempDir())
+	assert.ErrorIs(t, err, scaffold.ErrChecksum)
+
+	err = scaffold.NewArchive().CloneWithContext(context.Background(), srv.URL+"/missing.zip", t.TempDir())
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = scaffold.NewArchive().CloneWithContext(ctx, srv.URL+"/app.tar.gz", t.TempDir())
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,115 @@
+package scaffold
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Type is the type of a project that has a starter spec.
+type Type string
+
+const (
+	// Go is a project with a go.mod.
+	Go Type = "go"
+	// Node is a project with a package.json.
+	Node Type = "node"
+	// Docker is a project with a Dockerfile.
+	Docker Type = "docker"
+)
+
+// markers are the files that identify the types of projects, in the order of the tasks in the starter.
+var markers = []struct {
+	file string
+	typ  Type
+}{
+	{"go.mod", Go},
+	{"package.json", Node},
+	{"Dockerfile", Docker},
+}
+
+type starterTask struct {
+	name  string
+	desc  string
+	deps  []string
+	steps []string
+}
+
+var starterTasks = map[Type][]starterTask{
+	Go: {
+		{name: "build", desc: "Build the packages", steps: []string{"go build ./..."}},
+		{name: "test", desc: "Run the tests", steps: []string{"go test -race ./..."}},
+		{name: "lint", desc: "Check the code", steps: []string{"go vet ./..."}},
+	},
+	Node: {
+		{name: "install", desc: "Install the dependencies", steps: []string{"npm ci"}},
+		{name: "build", desc: "Build the package", deps: []string{"install"}, steps: []string{"npm run build"}},
+		{name: "test", desc: "Run the tests", deps: []string{"install"}, steps: []string{"npm test"}},
+	},
+	Docker: {
+		{name: "docker-build", desc: "Build the image", steps: []string{"docker build -t {{.image}} ."}},
+	},
+}
+
+// Detect returns the types of the project in the directory.
+func Detect(dir string) []Type {
+	var types []Type
+	for _, m := range markers {
+		if _, err := os.Stat(filepath.Join(dir, m.file)); err == nil {
+			types = append(types, m.typ)
+		}
+	}
+
+	return types
+}
+
+// Starter returns a spec with the tasks for the types of a project.
+// The first task is the default task. A task of a later type that has
+// the name of an existing task is prefixed with the type.
+func Starter(name string, types ...Type) []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "spec: 1\nversion: 0.0.1\n")
+
+	for _, typ := range types {
+		if typ == Docker {
+			fmt.Fprintf(&b, "vars:\n  image: %s\n", name)
+			break
+		}
+	}
+
+	if len(types) == 0 {
+		b.WriteString("tasks: {}\n")
+		return b.Bytes()
+	}
+
+	b.WriteString("tasks:\n")
+
+	names := make(map[string]bool)
+	for _, typ := range types {
+		for _, t := range starterTasks[typ] {
+			n := t.name
+			if names[n] {
+				n = string(typ) + "-" + n
+			}
+
+			fmt.Fprintf(&b, "  %s:\n    desc: %s\n", n, t.desc)
+			if len(names) == 0 {
+				b.WriteString("    default: true\n")
+			}
+			if len(t.deps) > 0 {
+				fmt.Fprintf(&b, "    depends-on: [%s]\n", strings.Join(t.deps, ", "))
+			}
+			b.WriteString("    steps:\n")
+			for _, s := range t.steps {
+				fmt.Fprintf(&b, "      - cmd: %s\n", s)
+			}
+
+			names[n] = true
+		}
+	}
+
+	return b.Bytes()
+}
@@ -0,0 +1,201 @@
+package spec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"mvdan.cc/sh/expand"
+	"mvdan.cc/sh/interp"
+)
+
+// execModule runs the programs of the builtin shell with runProcess.
+func execModule(opts *RunOpts) interp.ModuleExec {
+	return func(ctx context.Context, path string, args []string) error {
+		mc, _ := interp.FromModuleContext(ctx)
+		if path == "" {
+			fmt.Fprintf(mc.Stderr, "%q: executable file not found in $PATH\n", args[0])
+			return interp.ExitStatus(127)
+		}
+
+		cmd := &exec.Cmd{
+			Path:   path,
+			Args:   args,
+			Env:    environ(mc.Env),
+			Dir:    mc.Dir,
+			Stdin:  mc.Stdin,
+			Stdout: mc.Stdout,
+			Stderr: mc.Stderr,
+		}
+
+		err := runProcess(ctx, cmd, opts)
+
+		var execErr *exec.Error
+		if errors.As(err, &execErr) {
+			fmt.Fprintf(mc.Stderr, "%v\n", err)
+			return interp.ExitStatus(127)
+		}
+
+		return err
+	}
+}
+
+// runProcess runs the command in its own process group. If the context
+// is done, the term signal is sent to the process group. The kill signal
+// is sent if the processes have not exited after the grace period, or
+// when the kill channel of the options is closed, also if the context
+// is not canceled, e.g. for deferred steps.
+// A command that reads from a terminal only gets the signals itself.
+// A non-zero exit code is returned as interp.ExitStatus.
+func runProcess(ctx context.Context, cmd *exec.Cmd, opts *RunOpts) error {
+	grouped := setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return &exec.Error{Name: cmd.Path, Err: err}
+	}
+
+	exited := make(chan struct{})
+	stopped := make(chan struct{})
+
+	signal := func(sig os.Signal) {
+		if grouped {
+			_ = signalProcessGroup(cmd, sig)
+			return
+		}
+		_ = cmd.Process.Signal(sig)
+	}
+
+	alive := func() bool {
+		if grouped {
+			return processGroupAlive(cmd)
+		}
+
+		select {
+		case <-exited:
+			return false
+		default:
+			return true
+		}
+	}
+
+	go func() {
+		defer close(stopped)
+
+		select {
+		case <-exited:
+			return
+		case <-opts.Kill:
+			signal(opts.KillSignal)
+			return
+		case <-ctx.Done():
+		}
+
+		signal(opts.TermSignal)
+
+		// the group can outlive the process, e.g. if children ignore the signal
+		deadline := time.NewTimer(opts.GracePeriod)
+		defer deadline.Stop()
+
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-deadline.C:
+				signal(opts.KillSignal)
+				return
+			case <-opts.Kill:
+				signal(opts.KillSignal)
+				return
+			case <-ticker.C:
+				if !alive() {
+					return
+				}
+			}
+		}
+	}()
+
+	err := cmd.Wait()
+	close(exited)
+	<-stopped
+
+	switch x := err.(type) {
+	case nil:
+		return nil
+	case *exec.ExitError:
+		if status, ok := x.Sys().(syscall.WaitStatus); ok {
+			if status.Signaled() && ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if status.Signaled() && killed(opts.Kill) {
+				return context.Canceled
+			}
+
+			return interp.ExitStatus(status.ExitStatus())
+		}
+
+		return interp.ExitStatus(1)
+	default:
+		return err
+	}
+}
+
+// ExitCode returns the exit code of a failed command.
+func ExitCode(err error) (int, bool) {
+	var status interp.ExitStatus
+	if errors.As(err, &status) {
+		return int(status), true
+	}
+
+	var shellStatus interp.ShellExitStatus
+	if errors.As(err, &shellStatus) {
+		return int(shellStatus), true
+	}
+
+	return 0, false
+}
+
+func environ(env expand.Environ) []string {
+	list := make([]string, 0, 32)
+	env.Each(func(name string, vr expand.Variable) bool {
+		if vr.Exported {
+			list = append(list, name+"="+vr.String())
+		}
+		return true
+	})
+
+	return list
+}
+
+// detachedCtx is a context that keeps the values but not the cancellation of its parent.
+type detachedCtx struct {
+	parent context.Context
+}
+
+func (detachedCtx) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedCtx) Done() <-chan struct{}       { return nil }
+func (detachedCtx) Err() error                  { return nil }
+
+func (c detachedCtx) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// killed reports if the kill channel is closed.
+func killed(kill <-chan struct{}) bool {
+	select {
+	case <-kill:
+		return true
+	default:
+		return false
+	}
+}
+
+// withoutCancel returns a context that is not canceled when ctx is canceled.
+func withoutCancel(ctx context.Context) context.Context {
+	return detachedCtx{parent: ctx}
+}
@@ -0,0 +1,38 @@
+//go:build !windows
+
+package spec
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// setProcessGroup puts the command in its own process group and reports if it did.
+// A command that reads from a terminal stays in the foreground process group,
+// as a background process group would be stopped with SIGTTIN on reading.
+func setProcessGroup(cmd *exec.Cmd) bool {
+	if f, ok := cmd.Stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		return false
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	return true
+}
+
+func signalProcessGroup(cmd *exec.Cmd, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return cmd.Process.Signal(sig)
+	}
+
+	// the negative pid addresses the process group
+	return syscall.Kill(-cmd.Process.Pid, s)
+}
+
+func processGroupAlive(cmd *exec.Cmd) bool {
+	return syscall.Kill(-cmd.Process.Pid, 0) == nil
+}
@@ -0,0 +1,125 @@
+//go:build !windows
+
+package spec
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"mvdan.cc/sh/interp"
+)
+
+func TestRunProcess(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not installed")
+	}
+
+	// the processes ignore the term signal before they write the started file
+	ignore := `trap "" TERM; : > started; exec sleep 10`
+
+	tests := []struct {
+		name    string
+		script  string
+		grace   time.Duration
+		kill    bool
+		wait    bool
+		timeout time.Duration
+		err     error
+	}{
+		{name: "exit", script: "exit 3", err: interp.ExitStatus(3)},
+		{name: "term", script: ": > started; exec sleep 10", grace: 5 * time.Second, timeout: 3 * time.Second, err: context.Canceled},
+		{name: "grace period", script: ignore, grace: 200 * time.Millisecond, wait: true, timeout: 3 * time.Second, err: context.Canceled},
+		{name: "kill", script: ignore, grace: time.Minute, kill: true, timeout: 3 * time.Second, err: context.Canceled},
+		{name: "group", script: `(trap "" TERM; sleep 10) & : > started; wait`, grace: 200 * time.Millisecond, wait: true, timeout: 3 * time.Second, err: context.Canceled},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			kill := make(chan struct{})
+			opts := new(RunOpts)
+			opts.Configure(WithGracePeriod(tc.grace), WithKill(kill))
+
+			cmd := exec.Command(sh, "-c", tc.script)
+			cmd.Dir = dir
+
+			if tc.timeout > 0 {
+				go func() {
+					assert.Eventually(t, func() bool {
+						_, err := os.Stat(filepath.Join(dir, "started"))
+						return err == nil
+					}, time.Second, 10*time.Millisecond)
+					cancel()
+
+					if tc.kill {
+						time.Sleep(100 * time.Millisecond)
+						close(kill)
+					}
+				}()
+			}
+
+			start := time.Now()
+			err := runProcess(ctx, cmd, opts)
+			elapsed := time.Since(start)
+
+			assert.ErrorIs(t, err, tc.err)
+			if tc.timeout > 0 {
+				assert.Less(t, elapsed, tc.timeout)
+			}
+
+			// the kill signal is only sent after the grace period
+			if tc.wait {
+				assert.GreaterOrEqual(t, elapsed, tc.grace)
+			}
+
+			// no process of the group survives, the orphans are reaped by init
+			assert.Eventually(t, func() bool { return !processGroupAlive(cmd) }, 5*time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestRunProcess_KillWithoutCancel(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not installed")
+	}
+
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	kill := make(chan struct{})
+	opts := new(RunOpts)
+	opts.Configure(WithGracePeriod(time.Minute), WithKill(kill))
+
+	// deferred steps run with a context that is not canceled
+	cmd := exec.Command(sh, "-c", ": > started; exec sleep 30")
+	cmd.Dir = dir
+
+	go func() {
+		assert.Eventually(t, func() bool {
+			_, err := os.Stat(filepath.Join(dir, "started"))
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+		close(kill)
+	}()
+
+	start := time.Now()
+	err = runProcess(withoutCancel(ctx), cmd, opts)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 3*time.Second)
+}
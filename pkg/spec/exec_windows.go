@@ -0,0 +1,17 @@
+package spec
+
+import (
+	"os"
+	"os/exec"
+)
+
+func setProcessGroup(cmd *exec.Cmd) bool { return false }
+
+func signalProcessGroup(cmd *exec.Cmd, sig os.Signal) error {
+	// signals other than kill are not supported on windows
+	return cmd.Process.Kill()
+}
+
+func processGroupAlive(cmd *exec.Cmd) bool {
+	return false
+}
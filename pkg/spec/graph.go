@@ -0,0 +1,127 @@
+package spec
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrCyclicDependency = fmt.Errorf("cyclic dependency")
+)
+
+// Graph ...
+type Graph struct {
+	// Nodes are the names of the tasks in the graph.
+	Nodes []string
+	// Edges are the dependencies between the tasks.
+	Edges []Edge
+}
+
+// Edge ...
+type Edge struct {
+	// From is the task that depends on another task.
+	From string
+	// To is the task that is depended on.
+	To string
+}
+
+// Names returns the sorted names of all tasks.
+func (s *Spec) Names() []string {
+	names := make([]string, 0, len(s.Tasks))
+	for k := range s.Tasks {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Graph resolves the dependency graph for the given tasks.
+// The graph contains all tasks if no names are provided.
+func (s *Spec) Graph(names ...string) (*Graph, error) {
+	if len(names) == 0 {
+		names = s.Names()
+	}
+
+	g := new(Graph)
+	visited := make(map[string]bool)
+	visiting := make(map[string]bool)
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		if visited[name] {
+			return nil
+		}
+
+		if visiting[name] {
+			return fmt.Errorf("%w: %s", ErrCyclicDependency, name)
+		}
+
+		t, ok := s.Tasks[name]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrTaskNotFound, name)
+		}
+
+		visiting[name] = true
+		for _, dep := range t.DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+			g.Edges = append(g.Edges, Edge{From: name, To: dep})
+		}
+		visiting[name] = false
+
+		visited[name] = true
+		g.Nodes = append(g.Nodes, name)
+
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+// WriteDot writes the graph in the Graphviz DOT format.
+func (g *Graph) WriteDot(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("digraph run {\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %q;\n", n)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %q -> %q;\n", e.From, e.To)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart.
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	var b strings.Builder
+
+	ids := make(map[string]string, len(g.Nodes))
+
+	b.WriteString("graph TD\n")
+	for i, n := range g.Nodes {
+		ids[n] = fmt.Sprintf("t%d", i)
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", ids[n], strings.ReplaceAll(n, `"`, "#quot;"))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[e.From], ids[e.To])
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
@@ -0,0 +1,71 @@
+package spec
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// inheritAlways are the variables that are always inherited from the process.
+var inheritAlways = []string{"PATH", "HOME"}
+
+// EnvInherit selects the variables of the environment of the process
+// that are passed to the steps. It is `all`, `none` or a list of names.
+// PATH and HOME are always passed.
+type EnvInherit struct {
+	// All passes all variables.
+	All bool
+	// Names are the names of the passed variables if not all are passed.
+	Names []string
+}
+
+// UnmarshalYAML ...
+func (e *EnvInherit) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		switch value.Value {
+		case "all":
+			e.All = true
+			return nil
+		case "none":
+			return nil
+		}
+
+		return fmt.Errorf("line %d: invalid env-inherit: %s", value.Line, value.Value)
+	}
+
+	return value.Decode(&e.Names)
+}
+
+// MarshalYAML ...
+func (e EnvInherit) MarshalYAML() (interface{}, error) {
+	if e.All {
+		return "all", nil
+	}
+
+	if len(e.Names) == 0 {
+		return "none", nil
+	}
+
+	return e.Names, nil
+}
+
+// Filter returns the inherited variables of the environment.
+// All variables are inherited if e is nil.
+func (e *EnvInherit) Filter(environ Env) Env {
+	env := make(Env)
+
+	if e == nil || e.All {
+		env.Merge(environ)
+		return env
+	}
+
+	for _, names := range [][]string{inheritAlways, e.Names} {
+		for _, name := range names {
+			if v, ok := environ[name]; ok {
+				env[name] = v
+			}
+		}
+	}
+
+	return env
+}
@@ -0,0 +1,183 @@
+package spec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Matrix runs a task for every combination of the values of its axes.
+//
+//	matrix:
+//	  goos: [linux, darwin]
+//	  goarch: [amd64, arm64]
+//	  exclude:
+//	    - goos: darwin
+//	      goarch: amd64
+//	  include:
+//	    - goos: windows
+//	      goarch: amd64
+type Matrix struct {
+	// Axes are the axes in the order of the spec.
+	Axes []Axis
+	// Include extends the matching combinations or adds a new combination.
+	Include []Vars
+	// Exclude removes the matching combinations.
+	Exclude []Vars
+}
+
+// Axis ...
+type Axis struct {
+	Name   string
+	Values []string
+}
+
+// UnmarshalYAML ...
+func (m *Matrix) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: matrix must be a map", value.Line)
+	}
+
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key, node := value.Content[i].Value, value.Content[i+1]
+
+		switch key {
+		case "include":
+			if err := node.Decode(&m.Include); err != nil {
+				return err
+			}
+		case "exclude":
+			if err := node.Decode(&m.Exclude); err != nil {
+				return err
+			}
+		default:
+			var values []string
+			if err := node.Decode(&values); err != nil {
+				return err
+			}
+			m.Axes = append(m.Axes, Axis{Name: key, Values: values})
+		}
+	}
+
+	return nil
+}
+
+// Combinations returns the values of all combinations of the axes.
+//
+// Excluded combinations are removed before the includes are applied.
+// An include is added to every combination it does not change the values of
+// the axes of. It is a combination of its own if there is no such combination.
+func (m *Matrix) Combinations() []Vars {
+	combinations := []Vars{}
+	if len(m.Axes) > 0 {
+		combinations = append(combinations, Vars{})
+	}
+
+	for _, axis := range m.Axes {
+		next := make([]Vars, 0, len(combinations)*len(axis.Values))
+		for _, c := range combinations {
+			for _, v := range axis.Values {
+				cc := Vars{axis.Name: v}
+				cc.Merge(c)
+				next = append(next, cc)
+			}
+		}
+		combinations = next
+	}
+
+	filtered := combinations[:0]
+	for _, c := range combinations {
+		if !m.excluded(c) {
+			filtered = append(filtered, c)
+		}
+	}
+	combinations = filtered
+
+	for _, include := range m.Include {
+		added := false
+
+		for _, c := range combinations {
+			if m.extends(c, include) {
+				c.Merge(include)
+				added = true
+			}
+		}
+
+		if !added {
+			c := make(Vars)
+			c.Merge(include)
+			combinations = append(combinations, c)
+		}
+	}
+
+	return combinations
+}
+
+// Name returns the name of the instance of a task for the values of a combination.
+// The values of the axes are in the order of the spec, followed by the sorted
+// values that are only added by an include.
+func (m *Matrix) Name(task string, values Vars) string {
+	seen := make(map[string]bool, len(values))
+	pairs := make([]string, 0, len(values))
+
+	for _, axis := range m.Axes {
+		if v, ok := values[axis.Name]; ok {
+			pairs = append(pairs, axis.Name+"="+v)
+			seen[axis.Name] = true
+		}
+	}
+
+	extra := make([]string, 0)
+	for k, v := range values {
+		if !seen[k] {
+			extra = append(extra, k+"="+v)
+		}
+	}
+	sort.Strings(extra)
+
+	return fmt.Sprintf("%s[%s]", task, strings.Join(append(pairs, extra...), ","))
+}
+
+// Env returns the values of a combination as environment variables.
+// The names are in upper case, e.g. `goos` is `GOOS`.
+func (m *Matrix) Env(values Vars) Env {
+	env := make(Env, len(values))
+	for k, v := range values {
+		env[strings.ToUpper(k)] = v
+	}
+
+	return env
+}
+
+func (m *Matrix) excluded(c Vars) bool {
+	for _, exclude := range m.Exclude {
+		if matches(c, exclude) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// extends returns true if the include does not change the values of the axes of c.
+func (m *Matrix) extends(c, include Vars) bool {
+	for _, axis := range m.Axes {
+		if v, ok := include[axis.Name]; ok && c[axis.Name] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matches(c, values Vars) bool {
+	for k, v := range values {
+		if c[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
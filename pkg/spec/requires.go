@@ -0,0 +1,97 @@
+package spec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Requires are the preconditions of a task.
+type Requires struct {
+	// Bins are the executables that must be in the PATH.
+	Bins []string `yaml:"bins,omitempty"`
+	// Env are the environment variables that must be set.
+	Env []string `yaml:"env,omitempty"`
+	// Files are the files that must exist, relative to the working directory.
+	Files []string `yaml:"files,omitempty"`
+	// Preconditions are commands that must succeed.
+	Preconditions []Precondition `yaml:"preconditions,omitempty"`
+}
+
+// Precondition is a command that must succeed, with a message if it fails.
+type Precondition struct {
+	Cmd string `yaml:"cmd"`
+	Msg string `yaml:"msg,omitempty"`
+}
+
+// RequiresError is the error of a task with unmet preconditions.
+type RequiresError struct {
+	// Unmet are the messages of all unmet preconditions.
+	Unmet []string
+}
+
+// Error ...
+func (e *RequiresError) Error() string {
+	return fmt.Sprintf("unmet requirements:\n  - %s", strings.Join(e.Unmet, "\n  - "))
+}
+
+// Check checks all preconditions with the environment, working directory and shell
+// of the options. It returns a RequiresError with all unmet preconditions.
+func (r *Requires) Check(ctx context.Context, opts *RunOpts) error {
+	if r == nil {
+		return nil
+	}
+
+	unmet := make([]string, 0)
+
+	for _, bin := range r.Bins {
+		if _, err := lookPath(bin, opts.Env["PATH"]); err != nil {
+			unmet = append(unmet, fmt.Sprintf("executable %s is not in the PATH, please install it", bin))
+		}
+	}
+
+	for _, name := range r.Env {
+		if v, ok := opts.Env[name]; !ok || v == "" {
+			unmet = append(unmet, fmt.Sprintf("environment variable %s is not set", name))
+		}
+	}
+
+	for _, file := range r.Files {
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(opts.WorkingDir.String(), path)
+		}
+
+		if _, err := os.Stat(path); err != nil {
+			unmet = append(unmet, fmt.Sprintf("file %s does not exist", file))
+		}
+	}
+
+	for _, p := range r.Preconditions {
+		o := *opts
+		o.Stdout, o.Stderr = io.Discard, io.Discard
+
+		err := check(ctx, p.Cmd, &o)
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+
+		if err != nil {
+			msg := p.Msg
+			if msg == "" {
+				msg = fmt.Sprintf("precondition %q has failed: %s", p.Cmd, err)
+			}
+			unmet = append(unmet, msg)
+		}
+	}
+
+	if len(unmet) > 0 {
+		return &RequiresError{Unmet: unmet}
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package spec
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Retry is the retry policy of a step.
+type Retry struct {
+	// Attempts is the maximum number of attempts, including the first.
+	Attempts int `yaml:"attempts"`
+	// Delay is the time to wait before the second attempt.
+	Delay time.Duration `yaml:"delay,omitempty"`
+	// Backoff is the factor the delay is multiplied with after each attempt.
+	Backoff float64 `yaml:"backoff,omitempty"`
+	// OnExitCodes restricts the retries to these exit codes. All failures are retried if empty.
+	OnExitCodes []int `yaml:"on-exit-codes,omitempty"`
+}
+
+// RetryError is the error of a step that has failed after all attempts.
+type RetryError struct {
+	// Errors are the errors of all attempts.
+	Errors []error
+}
+
+// Error ...
+func (e *RetryError) Error() string {
+	ss := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		ss[i] = fmt.Sprintf("attempt %d: %s", i+1, err)
+	}
+
+	return fmt.Sprintf("failed after %d attempts (%s)", len(e.Errors), strings.Join(ss, "; "))
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *RetryError) Unwrap() error {
+	return e.Errors[len(e.Errors)-1]
+}
+
+// Do runs fn until it succeeds or the policy is exhausted.
+func (r *Retry) Do(ctx context.Context, logger *zap.Logger, fn func() error) error {
+	if r == nil || r.Attempts <= 1 {
+		return fn()
+	}
+
+	delay := r.Delay
+	errs := make([]error, 0, r.Attempts)
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+
+		if attempt >= r.Attempts || !r.retryable(err) || ctx.Err() != nil {
+			if len(errs) == 1 {
+				return err
+			}
+
+			return &RetryError{Errors: errs}
+		}
+
+		logger.Warn("attempt failed, retrying", zap.Int("attempt", attempt), zap.Int("attempts", r.Attempts), zap.Duration("delay", delay), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return &RetryError{Errors: append(errs, ctx.Err())}
+		case <-time.After(delay):
+		}
+
+		if r.Backoff > 0 {
+			delay = time.Duration(float64(delay) * r.Backoff)
+		}
+	}
+}
+
+func (r *Retry) retryable(err error) bool {
+	if len(r.OnExitCodes) == 0 {
+		return true
+	}
+
+	status, ok := ExitCode(err)
+	if !ok {
+		return false
+	}
+
+	for _, code := range r.OnExitCodes {
+		if code == status {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,212 @@
+package spec
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mvdan.cc/sh/interp"
+	"mvdan.cc/sh/syntax"
+)
+
+// The `errexit` option of the interpreter also stops at failing conditions,
+// so it is emulated with a check after the statements of the script, which
+// exits with the status of a failed statement. The state is kept in variables
+// of the shell, so that it is copied to subshells like the options of a shell.
+const (
+	// errexitVar is 1 if the script stops at a failed statement.
+	// It is changed by `set -e` and `set +e`.
+	errexitVar = "__run_errexit"
+	// condVar counts the conditions that are running. Like `set -e`, a failure
+	// in a condition of `if`, `while` or `until`, in a negated statement or in any
+	// but the last command of an `&&` or `||` list does not stop the script,
+	// also in the functions that are called by it.
+	condVar = "__run_cond"
+	// statusVar keeps the status of a statement while it is checked.
+	statusVar = "__run_status"
+	// returnFunc returns with a status, to restore the status after a check.
+	returnFunc = "__run_return"
+)
+
+// runScript runs the script with the emulated `errexit` option.
+func runScript(ctx context.Context, r *interp.Runner, f *syntax.File, errexit bool) error {
+	errexitList(&f.StmtList)
+
+	on := 0
+	if errexit {
+		on = 1
+	}
+
+	prelude := parseStmts(fmt.Sprintf(
+		"%s() { return $1; }\n%s=%d %s=0 %s=0",
+		returnFunc, errexitVar, on, condVar, statusVar,
+	))
+	f.Stmts = append(prelude, f.Stmts...)
+
+	return r.Run(ctx, f)
+}
+
+func errexitList(list *syntax.StmtList) {
+	for i, stmt := range list.Stmts {
+		list.Stmts[i] = errexitStmt(stmt)
+	}
+}
+
+// errexitStmt returns the statement followed by the check, and adds the checks
+// to the statements in the bodies and conditions of the compound commands.
+func errexitStmt(stmt *syntax.Stmt) *syntax.Stmt {
+	if stmt.Background || stmt.Coprocess || stmt.Cmd == nil {
+		return stmt
+	}
+
+	if stmt.Negated {
+		inner := *stmt
+		inner.Negated = false
+
+		return &syntax.Stmt{
+			Position: stmt.Position,
+			Negated:  true,
+			Cmd:      &syntax.Block{StmtList: syntax.StmtList{Stmts: condition(errexitStmt(&inner))}},
+		}
+	}
+
+	switch x := stmt.Cmd.(type) {
+	case *syntax.BinaryCmd:
+		if x.Op == syntax.AndStmt || x.Op == syntax.OrStmt {
+			x.X = &syntax.Stmt{
+				Position: x.X.Position,
+				Cmd:      &syntax.Block{StmtList: syntax.StmtList{Stmts: condition(errexitStmt(x.X))}},
+			}
+			x.Y = errexitStmt(x.Y)
+
+			return stmt
+		}
+	case *syntax.Block:
+		errexitList(&x.StmtList)
+		return stmt
+	case *syntax.Subshell:
+		errexitList(&x.StmtList)
+	case *syntax.IfClause:
+		errexitList(&x.Cond)
+		x.Cond.Stmts = condition(x.Cond.Stmts...)
+		errexitList(&x.Then)
+		errexitList(&x.Else)
+
+		return stmt
+	case *syntax.WhileClause:
+		errexitList(&x.Cond)
+		x.Cond.Stmts = condition(x.Cond.Stmts...)
+		errexitList(&x.Do)
+
+		return stmt
+	case *syntax.ForClause:
+		errexitList(&x.Do)
+		return stmt
+	case *syntax.CaseClause:
+		for _, item := range x.Items {
+			errexitList(&item.StmtList)
+		}
+
+		return stmt
+	case *syntax.FuncDecl:
+		x.Body = errexitStmt(x.Body)
+		return stmt
+	case *syntax.CallExpr:
+		stmt = errexitSet(stmt, x)
+	}
+
+	check := parseStmts(fmt.Sprintf(
+		"{ %[1]s=$?; if [ \"$%[2]s\" = 1 ] && [ $((%[3]s)) = 0 ]; then exit $%[1]s; fi; %[4]s $%[1]s; }",
+		statusVar, errexitVar, condVar, returnFunc,
+	))
+
+	return &syntax.Stmt{
+		Position: stmt.Position,
+		Cmd:      &syntax.BinaryCmd{Op: syntax.OrStmt, X: stmt, Y: check[0]},
+	}
+}
+
+// condition returns the statements of a condition, which are not checked
+// while they are running. The status of the last statement is kept.
+func condition(stmts ...*syntax.Stmt) []*syntax.Stmt {
+	list := parseStmts(fmt.Sprintf("%[1]s=$((%[1]s+1))", condVar))
+	list = append(list, stmts...)
+
+	return append(list, parseStmts(fmt.Sprintf(
+		"%[1]s=$?\n%[2]s=$((%[2]s-1))\n%[3]s $%[1]s",
+		statusVar, condVar, returnFunc,
+	))...)
+}
+
+// errexitSet removes `-e` and `-o errexit` from a `set` with literal flags,
+// and sets the errexitVar instead.
+func errexitSet(stmt *syntax.Stmt, call *syntax.CallExpr) *syntax.Stmt {
+	if len(call.Args) < 2 || call.Args[0].Lit() != "set" {
+		return stmt
+	}
+
+	args := []*syntax.Word{call.Args[0]}
+	on := -1
+
+	for i := 1; i < len(call.Args); i++ {
+		flag := call.Args[i].Lit()
+		if len(flag) < 2 || (flag[0] != '-' && flag[0] != '+') || flag == "--" {
+			args = append(args, call.Args[i:]...)
+			break
+		}
+
+		enable := 0
+		if flag[0] == '-' {
+			enable = 1
+		}
+
+		if flag[1:] == "o" && i+1 < len(call.Args) && call.Args[i+1].Lit() == "errexit" {
+			on = enable
+			i++
+
+			continue
+		}
+
+		if strings.Contains(flag[1:], "e") {
+			on = enable
+			flag = strings.ReplaceAll(flag, "e", "")
+		}
+
+		if len(flag) > 1 {
+			args = append(args, &syntax.Word{Parts: []syntax.WordPart{&syntax.Lit{ValuePos: call.Args[i].Pos(), Value: flag}}})
+		}
+
+		// the name of the option is the next argument
+		if strings.Contains(flag[1:], "o") && i+1 < len(call.Args) {
+			i++
+			args = append(args, call.Args[i])
+		}
+	}
+
+	if on < 0 {
+		return stmt
+	}
+
+	assign := parseStmts(fmt.Sprintf("%s=%d", errexitVar, on))[0]
+	if len(args) == 1 {
+		assign.Position = stmt.Position
+		assign.Redirs = stmt.Redirs
+
+		return assign
+	}
+	call.Args = args
+
+	return &syntax.Stmt{
+		Position: stmt.Position,
+		Cmd:      &syntax.BinaryCmd{Op: syntax.AndStmt, X: stmt, Y: assign},
+	}
+}
+
+func parseStmts(src string) []*syntax.Stmt {
+	f, err := syntax.NewParser().Parse(strings.NewReader(src), "")
+	if err != nil {
+		panic(err)
+	}
+
+	return f.Stmts
+}
@@ -0,0 +1,100 @@
+package spec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSecretNotFound = fmt.Errorf("secret not found")
+)
+
+// DrySecret is the value of the secrets with a command in a dry run.
+const DrySecret = "<dry-run>"
+
+// Secrets ...
+type Secrets map[string]Secret
+
+// Secret is a value that is exposed to the steps as environment variable
+// and is masked in all output. The value is read from exactly one source.
+type Secret struct {
+	// Env is the name of an environment variable of the process.
+	Env string `yaml:"env,omitempty"`
+	// File is the path to a file relative to the working directory.
+	File string `yaml:"file,omitempty"`
+	// Cmd is a command that prints the value to stdout.
+	Cmd string `yaml:"cmd,omitempty"`
+}
+
+// Resolve reads the values of all secrets. The working directory, environment
+// and stderr of the options are used to run the commands of the secrets.
+// The commands are not run in a dry run, and DrySecret is used instead.
+func (s Secrets) Resolve(ctx context.Context, opts ...RunOpt) (Env, error) {
+	options := new(RunOpts)
+	options.Configure(opts...)
+
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := make(Env, len(s))
+	for _, name := range names {
+		v, err := s[name].resolve(ctx, options)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: %w", name, err)
+		}
+		env[name] = v
+	}
+
+	return env, nil
+}
+
+func (s Secret) resolve(ctx context.Context, opts *RunOpts) (string, error) {
+	switch {
+	case s.Env != "":
+		v, ok := os.LookupEnv(s.Env)
+		if !ok {
+			return "", fmt.Errorf("%w: environment variable %s is not set", ErrSecretNotFound, s.Env)
+		}
+
+		return v, nil
+	case s.File != "":
+		path := s.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(opts.WorkingDir.String(), path)
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case s.Cmd != "" && opts.Dry:
+		return DrySecret, nil
+	case s.Cmd != "":
+		var out bytes.Buffer
+
+		o := *opts
+		o.Stdout = &out
+
+		step := &Step{}
+		if err := step.runCmd(ctx, s.Cmd, time.Duration(math.MaxInt), &o); err != nil {
+			return "", err
+		}
+
+		return strings.TrimRight(out.String(), "\r\n"), nil
+	default:
+		return "", fmt.Errorf("%w: no env, file or cmd", ErrSecretNotFound)
+	}
+}
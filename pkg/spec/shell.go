@@ -0,0 +1,139 @@
+package spec
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/katallaxie/run/pkg/utils"
+)
+
+// ScriptPlaceholder is replaced with the path to the script file in a custom shell.
+const ScriptPlaceholder = "{0}"
+
+// Shell is the shell that runs the cmd of a step.
+type Shell string
+
+const (
+	// ShellBuiltin is the builtin shell interpreter.
+	ShellBuiltin Shell = "builtin"
+	// ShellBash ...
+	ShellBash Shell = "bash"
+	// ShellSh ...
+	ShellSh Shell = "sh"
+	// ShellPython ...
+	ShellPython Shell = "python3"
+)
+
+var shells = map[Shell]string{
+	ShellBash:   "bash --noprofile --norc -eo pipefail {0}",
+	ShellSh:     "sh -e {0}",
+	ShellPython: "python3 {0}",
+}
+
+// Builtin returns true if the builtin interpreter is used.
+func (s Shell) Builtin() bool {
+	return s == "" || s == ShellBuiltin
+}
+
+// Args returns the command line to run the script file. A custom shell
+// is a command line with {0} as the placeholder for the script file,
+// e.g. `node {0}`. The script file is appended if there is no placeholder.
+func (s Shell) Args(script string) ([]string, error) {
+	tmpl, ok := shells[s]
+	if !ok {
+		tmpl = string(s)
+	}
+
+	if !strings.Contains(tmpl, ScriptPlaceholder) {
+		tmpl += " " + ScriptPlaceholder
+	}
+
+	args := strings.Fields(tmpl)
+	if len(args) < 2 {
+		return nil, fmt.Errorf("invalid shell: %s", s)
+	}
+
+	for i, arg := range args {
+		args[i] = strings.ReplaceAll(arg, ScriptPlaceholder, script)
+	}
+
+	return args, nil
+}
+
+func (s *Step) runShell(ctx context.Context, shell Shell, script string, timeout time.Duration, opts *RunOpts) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	f, err := os.CreateTemp("", "run-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(script)
+	if cerr := f.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	args, err := shell.Args(f.Name())
+	if err != nil {
+		return err
+	}
+	args = append(args, opts.Args...)
+
+	path, err := lookPath(args[0], opts.Env["PATH"])
+	if err != nil {
+		return err
+	}
+
+	cmd := &exec.Cmd{
+		Path:   path,
+		Args:   args,
+		Env:    utils.Strings(opts.Env),
+		Dir:    opts.WorkingDir.String(),
+		Stdin:  opts.Stdin,
+		Stdout: opts.Stdout,
+		Stderr: opts.Stderr,
+	}
+
+	return runProcess(ctx, cmd, opts)
+}
+
+// check runs a command that checks a task, e.g. a status command, with the shell of the options.
+func check(ctx context.Context, cmd string, opts *RunOpts) error {
+	step := &Step{}
+	if !opts.Shell.Builtin() {
+		return step.runShell(ctx, opts.Shell, cmd, time.Duration(math.MaxInt), opts)
+	}
+
+	return step.runCmd(ctx, cmd, time.Duration(math.MaxInt), opts)
+}
+
+// lookPath searches the executable in the PATH of the step.
+func lookPath(file, path string) (string, error) {
+	if strings.ContainsRune(file, filepath.Separator) || strings.Contains(file, "/") {
+		return exec.LookPath(file)
+	}
+
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+
+		if p, err := exec.LookPath(filepath.Join(dir, file)); err == nil {
+			return p, nil
+		}
+	}
+
+	return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+}
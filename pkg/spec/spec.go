@@ -2,21 +2,31 @@ package spec
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
-	"log"
 	"math"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/katallaxie/run/pkg/dotenv"
+	"github.com/katallaxie/run/pkg/mask"
+	"github.com/katallaxie/run/pkg/output"
 	"github.com/katallaxie/run/pkg/plugin"
+	"github.com/katallaxie/run/pkg/report"
 	"github.com/katallaxie/run/pkg/tmpl"
 	"github.com/katallaxie/run/pkg/utils"
 
 	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
 	"golang.org/x/exp/maps"
 	"gopkg.in/yaml.v3"
 	"mvdan.cc/sh/expand"
@@ -33,6 +43,11 @@ const (
 	DefaultFilename = ".run.yml"
 )
 
+// DefaultGracePeriod is the time processes have to exit after the term signal.
+const (
+	DefaultGracePeriod = 10 * time.Second
+)
+
 // Spec ...
 type Spec struct {
 	// Spec ...
@@ -57,6 +72,24 @@ type Spec struct {
 	Vars Vars `yaml:"vars"`
 	// Env ...
 	Env Env `yaml:"env"`
+	// EnvFile ...
+	EnvFile EnvFiles `yaml:"env-file,omitempty"`
+	// EnvInherit ...
+	EnvInherit *EnvInherit `yaml:"env-inherit,omitempty"`
+	// Secrets ...
+	Secrets Secrets `yaml:"secrets,omitempty"`
+	// Output ...
+	Output string `yaml:"output,omitempty"`
+	// Shell ...
+	Shell Shell `yaml:"shell,omitempty"`
+	// Cache ...
+	Cache Cache `yaml:"cache,omitempty"`
+	// OnSuccess are the tasks that run after all tasks have succeeded.
+	OnSuccess []string `yaml:"on-success,omitempty"`
+	// OnFailure are the tasks that run after a task has failed.
+	OnFailure []string `yaml:"on-failure,omitempty"`
+	// Path ...
+	Path string `yaml:"-"`
 }
 
 // Fields ...
@@ -120,6 +153,7 @@ func Load(file string) (*Spec, error) {
 	if err != nil {
 		return nil, err
 	}
+	spec.Path = file
 
 	return &spec, nil
 }
@@ -137,39 +171,30 @@ func (s *Spec) Default() []string {
 	return tt
 }
 
-// Find ...
+// Find returns the tasks with all their dependencies.
+// Dependencies are ordered before the tasks that depend on them.
 func (s *Spec) Find(names ...string) ([]string, error) {
-	all := make(map[string]bool)
-	tt := make([]string, 0)
-
-	for _, name := range names {
-		if _, exists := all[name]; exists {
-			continue
-		}
-
-		t, ok := s.Tasks[name]
-		if !ok {
-			return nil, ErrTaskNotFound
-		}
-
-		for _, dep := range t.DependsOn {
-			if _, exists := all[dep]; exists {
-				continue
-			}
-
-			d, ok := s.Tasks[dep]
-			if !ok {
-				return nil, ErrTaskNotFound
-			}
-			tt = append(tt, dep)
-			all[d.Name] = true
-		}
+	if len(names) == 0 {
+		return []string{}, nil
+	}
 
-		all[name] = true
-		tt = append(tt, name)
+	g, err := s.Graph(names...)
+	if err != nil {
+		return nil, err
 	}
 
-	return tt, nil
+	return g.Nodes, nil
+}
+
+// Cache configures the store of the outputs of the tasks.
+// The HTTP store at the URL is used if set, else the local directory.
+type Cache struct {
+	// Dir is the local directory of the cache.
+	Dir string `yaml:"dir,omitempty"`
+	// URL is the URL of the HTTP store.
+	URL string `yaml:"url,omitempty"`
+	// TokenEnv is the environment variable with the token for the HTTP store.
+	TokenEnv string `yaml:"token-env,omitempty"`
 }
 
 // Authors ...
@@ -187,14 +212,28 @@ type Task struct {
 	Default   bool      `yaml:"default"`
 	DependsOn DependsOn `yaml:"depends-on"`
 	Name      string    `yaml:"name"`
+	Desc      string    `yaml:"desc"`
 	Disabled  bool      `yaml:"disabled"`
 	Env       Env       `yaml:"env"`
+	EnvFile   EnvFiles  `yaml:"env-file,omitempty"`
+	Shell     Shell     `yaml:"shell,omitempty"`
 	Vars      Vars      `yaml:"vars"`
-	Templates Templates `yaml:"template,omitempty"`
 
-	Watch      Watch      `yaml:"watch"`
-	WorkingDir WorkingDir `yaml:"working-dir"`
-	Steps      Steps      `yaml:"steps"`
+	EnvInherit *EnvInherit `yaml:"env-inherit,omitempty"`
+	Matrix     *Matrix     `yaml:"matrix,omitempty"`
+	Sources    []string    `yaml:"sources,omitempty"`
+	Generates  []string    `yaml:"generates,omitempty"`
+	OnSuccess  []string    `yaml:"on-success,omitempty"`
+	OnFailure  []string    `yaml:"on-failure,omitempty"`
+	Requires   *Requires   `yaml:"requires,omitempty"`
+	Status     []string    `yaml:"status,omitempty"`
+	Templates  Templates   `yaml:"template,omitempty"`
+
+	AcceptsArgs bool       `yaml:"accepts-args,omitempty"`
+	Watch       Watch      `yaml:"watch"`
+	WorkingDir  WorkingDir `yaml:"working-dir"`
+	Steps       Steps      `yaml:"steps"`
+	Finally     Steps      `yaml:"finally,omitempty"`
 }
 
 // RunOpt ...
@@ -202,12 +241,31 @@ type RunOpt func(*RunOpts)
 
 // RunOpts ...
 type RunOpts struct {
-	WorkingDir WorkingDir
-	Vars       Vars
-	Env        Env
-	Stdin      io.Reader
-	Stdout     io.Writer
-	Stderr     io.Writer
+	WorkingDir   WorkingDir
+	Vars         Vars
+	Env          Env
+	OverrideVars Vars
+	OverrideEnv  Env
+	Environ      Env
+	EnvInherit   *EnvInherit
+	Shell        Shell
+	Matrix       Vars
+	Args         []string
+	Report       *report.Task
+	Dry          bool
+	Silent       bool
+	Masker       *mask.Masker
+	TermSignal   os.Signal
+	KillSignal   os.Signal
+	GracePeriod  time.Duration
+	Kill         <-chan struct{}
+	Logger       *zap.Logger
+	Stdin        io.Reader
+	Stdout       io.Writer
+	Stderr       io.Writer
+
+	// step is the report of the step that runs with the options.
+	step *report.Step
 }
 
 // Configure ...
@@ -215,6 +273,22 @@ func (o *RunOpts) Configure(opts ...RunOpt) {
 	for _, opt := range opts {
 		opt(o)
 	}
+
+	if o.Logger == nil {
+		o.Logger = zap.NewNop()
+	}
+
+	if o.TermSignal == nil {
+		o.TermSignal = syscall.SIGTERM
+	}
+
+	if o.KillSignal == nil {
+		o.KillSignal = os.Kill
+	}
+
+	if o.GracePeriod <= 0 {
+		o.GracePeriod = DefaultGracePeriod
+	}
 }
 
 // WithExtraVars ...
@@ -224,6 +298,125 @@ func WithExtraVars(vars Vars) RunOpt {
 	}
 }
 
+// WithOverrideVars sets variables that take precedence over the variables of the step.
+func WithOverrideVars(vars Vars) RunOpt {
+	return func(o *RunOpts) {
+		o.OverrideVars = vars
+	}
+}
+
+// WithOverrideEnv sets environment variables that take precedence over the environment of the step.
+func WithOverrideEnv(env Env) RunOpt {
+	return func(o *RunOpts) {
+		o.OverrideEnv = env
+	}
+}
+
+// WithShell ...
+func WithShell(shell Shell) RunOpt {
+	return func(o *RunOpts) {
+		o.Shell = shell
+	}
+}
+
+// WithMatrix sets the values of the matrix combination the task runs with.
+func WithMatrix(values Vars) RunOpt {
+	return func(o *RunOpts) {
+		o.Matrix = values
+	}
+}
+
+// WithArgs sets the arguments after `--` on the command line.
+func WithArgs(args []string) RunOpt {
+	return func(o *RunOpts) {
+		o.Args = args
+	}
+}
+
+// WithReport sets the report the task records its steps in.
+func WithReport(t *report.Task) RunOpt {
+	return func(o *RunOpts) {
+		o.Report = t
+	}
+}
+
+func withReportStep(s *report.Step) RunOpt {
+	return func(o *RunOpts) {
+		o.step = s
+	}
+}
+
+// WithEnviron sets the environment of the process the steps inherit from.
+func WithEnviron(env Env) RunOpt {
+	return func(o *RunOpts) {
+		o.Environ = env
+	}
+}
+
+// WithEnvInherit sets the variables of the environment of the process that are passed to the steps.
+func WithEnvInherit(e *EnvInherit) RunOpt {
+	return func(o *RunOpts) {
+		o.EnvInherit = e
+	}
+}
+
+// WithDry ...
+func WithDry(dry bool) RunOpt {
+	return func(o *RunOpts) {
+		o.Dry = dry
+	}
+}
+
+// WithSilent ...
+func WithSilent(silent bool) RunOpt {
+	return func(o *RunOpts) {
+		o.Silent = silent
+	}
+}
+
+// WithMasker ...
+func WithMasker(m *mask.Masker) RunOpt {
+	return func(o *RunOpts) {
+		o.Masker = m
+	}
+}
+
+// WithTermSignal sets the signal that is sent to the processes of a step when it is canceled.
+func WithTermSignal(sig os.Signal) RunOpt {
+	return func(o *RunOpts) {
+		o.TermSignal = sig
+	}
+}
+
+// WithKillSignal sets the signal that is sent to the processes of a step after the grace period.
+func WithKillSignal(sig os.Signal) RunOpt {
+	return func(o *RunOpts) {
+		o.KillSignal = sig
+	}
+}
+
+// WithGracePeriod ...
+func WithGracePeriod(d time.Duration) RunOpt {
+	return func(o *RunOpts) {
+		o.GracePeriod = d
+	}
+}
+
+// WithLogger ...
+func WithLogger(logger *zap.Logger) RunOpt {
+	return func(o *RunOpts) {
+		o.Logger = logger
+	}
+}
+
+// WithKill sets a channel that sends the kill signal to the processes of a canceled
+// step when it is closed, without waiting for the grace period.
+func WithKill(kill <-chan struct{}) RunOpt {
+	return func(o *RunOpts) {
+		o.Kill = kill
+	}
+}
+
 // WithStdin ...
 func WithStdin(r io.Reader) RunOpt {
 	return func(o *RunOpts) {
@@ -277,21 +470,242 @@ func (t *Task) Run(ctx context.Context, opts ...RunOpt) error {
 		}
 	}
 
-	for _, s := range t.Steps {
-		if err := s.Run(ctx, append(opts, WithExtraEnv(t.Env), WithExtraVars(t.Vars))...); err != nil {
+	env, vars, args, err := t.environment(options)
+	if err != nil {
+		return err
+	}
+
+	shell := t.shell(options)
+
+	inherit := t.inherit(options)
+
+	type step struct {
+		name   string
+		step   Step
+		report *report.Step
+	}
+
+	// deferred steps run after the other steps, followed by the finally steps
+	var main, deferred []step
+	for i, s := range t.Steps {
+		st := step{name: s.name(i), step: s, report: options.Report.AddStep(s.name(i))}
+		if s.Defer {
+			deferred = append(deferred, st)
+			continue
+		}
+		main = append(main, st)
+	}
+
+	for i, s := range t.Finally {
+		name := s.Id
+		if name == "" {
+			name = fmt.Sprintf("finally-%d", i)
+		}
+		deferred = append(deferred, step{name: name, step: s, report: options.Report.AddStep(name)})
+	}
+
+	if t.Requires != nil && !options.Dry {
+		o := *options
+		o.Env = inherit.Filter(options.Environ)
+		o.Env.Merge(env)
+		o.Env.Merge(options.OverrideEnv)
+		o.Shell = shell
+
+		if err := t.Requires.Check(ctx, &o); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	run := func(ctx context.Context, st step, vars Vars) error {
+		logger := options.Logger.With(zap.String("step", st.name))
+
+		err := st.step.Run(ctx, append(opts, WithExtraEnv(env), WithExtraVars(vars), WithShell(shell), WithEnvInherit(inherit), WithArgs(args), WithLogger(logger), withReportStep(st.report))...)
+		if err != nil {
+			return &StepError{Step: st.name, Err: err}
+		}
+
+		return nil
+	}
+
+	for _, st := range main {
+		if err = run(ctx, st, vars); err != nil {
+			break
+		}
+	}
+
+	if len(deferred) == 0 {
+		return err
+	}
+
+	outcome := make(Vars)
+	outcome.Merge(vars)
+	outcome.Merge(Outcome(ctx, err))
+
+	// deferred steps also run if the task is canceled
+	dctx := withoutCancel(ctx)
+
+	for _, st := range deferred {
+		// the error of the steps is not masked by the errors of the deferred steps
+		if derr := run(dctx, st, outcome); derr != nil && err == nil {
+			err = derr
+		}
+	}
+
+	return err
+}
+
+// environment returns the environment, variables and arguments of the task.
+func (t *Task) environment(options *RunOpts) (Env, Vars, []string, error) {
+	env := make(Env)
+	env.Merge(options.Env)
+
+	if err := t.EnvFile.Load(options.WorkingDir, env, t.inherit(options).Filter(options.Environ)); err != nil {
+		return nil, nil, nil, err
+	}
+	env.Merge(t.Env)
+
+	vars := make(Vars)
+	vars.Merge(options.Vars)
+	vars.Merge(t.Vars)
+
+	if t.Matrix != nil {
+		env.Merge(t.Matrix.Env(options.Matrix))
+		vars.Merge(options.Matrix)
+	}
+
+	args := []string{}
+	if t.AcceptsArgs {
+		args = options.Args
+		env["RUN_CLI_ARGS"] = utils.Quote(args)
+		vars["CLI_ARGS"] = utils.Quote(args)
+	}
+
+	return env, vars, args, nil
+}
+
+// shell returns the shell of the task, or else the shell of the options.
+func (t *Task) shell(opts *RunOpts) Shell {
+	if t.Shell != "" {
+		return t.Shell
+	}
+
+	return opts.Shell
+}
+
+// inherit returns the variables of the environment that the steps of the task inherit.
+func (t *Task) inherit(opts *RunOpts) *EnvInherit {
+	if t.EnvInherit != nil {
+		return t.EnvInherit
+	}
+
+	return opts.EnvInherit
+}
+
+// UpToDate runs the status commands of the task. The task is up to date if
+// it has status commands and all of them exit with 0.
+func (t *Task) UpToDate(ctx context.Context, opts ...RunOpt) (bool, error) {
+	if len(t.Status) == 0 {
+		return false, nil
+	}
+
+	options := new(RunOpts)
+	options.Configure(opts...)
+
+	env, vars, args, err := t.environment(options)
+	if err != nil {
+		return false, err
+	}
+
+	inherit := t.inherit(options)
+
+	o := *options
+	o.Env = inherit.Filter(options.Environ)
+	o.Env.Merge(env)
+	o.Env.Merge(options.OverrideEnv)
+	o.Args = args
+	o.Shell = t.shell(options)
+	o.Stdout, o.Stderr = io.Discard, io.Discard
+
+	vars.Merge(options.OverrideVars)
+
+	ff := make(tmpl.TmplFields)
+	for k, v := range vars {
+		ff[k] = v
+	}
+	gen := tmpl.New(tmpl.WithExtraFields(ff), tmpl.WithKeepUnknown())
+
+	for _, status := range t.Status {
+		cmd, err := gen.Apply(status)
+		if err != nil {
+			return false, err
+		}
+
+		err = check(ctx, cmd, &o)
+		if _, ok := ExitCode(err); ok {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// StepError is the error of the step that has failed a task.
+type StepError struct {
+	// Step is the id or index of the step.
+	Step string
+	Err  error
+}
+
+// Error ...
+func (e *StepError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap ...
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// Outcome returns the variables that describe the outcome of a task:
+// TASK_OUTCOME is `success`, `failed` or `canceled`, TASK_ERROR is the
+// error message and TASK_FAILED_STEP is the id or index of the failed step.
+func Outcome(ctx context.Context, err error) Vars {
+	vars := Vars{"TASK_OUTCOME": "success", "TASK_ERROR": "", "TASK_FAILED_STEP": ""}
+	if err == nil {
+		return vars
+	}
+
+	vars["TASK_OUTCOME"] = "failed"
+	if ctx.Err() != nil {
+		vars["TASK_OUTCOME"] = "canceled"
+	}
+	vars["TASK_ERROR"] = err.Error()
+
+	var stepErr *StepError
+	if errors.As(err, &stepErr) {
+		vars["TASK_FAILED_STEP"] = stepErr.Step
+	}
+
+	return vars
 }
 
 // Step ...
 type Step struct {
 	Cmd              string            `yaml:"cmd"`
 	ContinueOnError  bool              `yaml:"continue-on-error"`
+	Defer            bool              `yaml:"defer,omitempty"`
 	Env              Env               `yaml:"env"`
+	EnvFile          EnvFiles          `yaml:"env-file,omitempty"`
+	EnvInherit       *EnvInherit       `yaml:"env-inherit,omitempty"`
 	Id               string            `yaml:"id"`
+	Output           string            `yaml:"output,omitempty"`
+	Retry            *Retry            `yaml:"retry,omitempty"`
+	Shell            Shell             `yaml:"shell,omitempty"`
+	ErrExit          *bool             `yaml:"errexit,omitempty"`
 	If               string            `yaml:"if"`
 	TimeoutInSeconds int64             `yaml:"timeout-in-seconds"`
 	Uses             string            `yaml:"uses"`
@@ -300,57 +714,243 @@ type Step struct {
 	WorkingDir       WorkingDir        `yaml:"working-dir"`
 }
 
+func (s *Step) name(idx int) string {
+	if s.Id != "" {
+		return s.Id
+	}
+
+	return strconv.Itoa(idx)
+}
+
 // Run ...
 func (s *Step) Run(ctx context.Context, opts ...RunOpt) error {
 	options := new(RunOpts)
 	options.Configure(opts...)
 
-	maps.Copy(options.Env, s.Env)
-	maps.Copy(options.Vars, s.Vars)
+	rs := options.step
+	if rs == nil {
+		rs = new(report.Step)
+	}
+
+	start := time.Now()
+	options.Logger.Debug("running step")
+
+	attempts := 0
+	err := s.exec(ctx, options, &attempts)
+
+	status, code := report.StatusSuccess, 0
+	if err != nil {
+		status = report.StatusFailed
+		if c, ok := ExitCode(err); ok {
+			code = c
+		}
+	}
+
+	if attempts > 1 {
+		rs.Retries = attempts - 1
+	}
+	rs.Env = maps.Keys(options.Env)
+	sort.Strings(rs.Env)
+	rs.Finish(status, time.Since(start), code, err)
+
+	logger := options.Logger.With(zap.Duration("duration", time.Since(start)))
+	if err != nil && s.ContinueOnError {
+		logger.Warn("step failed, continuing", zap.Error(err))
+		return nil
+	}
 
+	if err != nil {
+		logger.Error("step failed", zap.Error(err))
+		return err
+	}
+	logger.Info("step finished")
+
+	return nil
+}
+
+func (s *Step) exec(ctx context.Context, options *RunOpts, attempts *int) error {
 	if s.WorkingDir != "" {
 		options.WorkingDir = s.WorkingDir
 	}
 
-	cmds := strings.Split(s.Cmd, "\n")
+	inherit := options.EnvInherit
+	if s.EnvInherit != nil {
+		inherit = s.EnvInherit
+	}
+
+	// every step gets its own copy of the environment and variables
+	env := inherit.Filter(options.Environ)
+	env.Merge(options.Env)
+
+	// the environment already has the inherited variables
+	if err := s.EnvFile.Load(options.WorkingDir, env, nil); err != nil {
+		return err
+	}
+	env.Merge(s.Env)
+	env.Merge(options.OverrideEnv)
+	options.Env = env
+
+	vars := make(Vars)
+	vars.Merge(options.Vars)
+	vars.Merge(s.Vars)
+	vars.Merge(options.OverrideVars)
+	options.Vars = vars
+
+	ff := make(tmpl.TmplFields)
+	for k, v := range vars {
+		ff[k] = v
+	}
+
+	gen := tmpl.New(tmpl.WithExtraFields(ff), tmpl.WithKeepUnknown())
+
+	cmd, err := gen.Apply(s.Cmd)
+	if err != nil {
+		return err
+	}
+
+	file, err := gen.Apply(s.Output)
+	if err != nil {
+		return err
+	}
+
+	stdout, stderr, closeWriters, err := s.writers(file, options)
+	if err != nil {
+		return err
+	}
+	options.Stdout, options.Stderr = stdout, stderr
+
 	timeout := time.Duration(time.Nanosecond * math.MaxInt)
 	if s.TimeoutInSeconds > 0 {
 		timeout = time.Duration(time.Second * time.Duration(s.TimeoutInSeconds))
 	}
 
-	if s.Uses != "" {
-		err := s.runRemote(ctx, s.Uses, timeout)
-		if err != nil && !s.ContinueOnError {
-			return err
+	err = s.Retry.Do(ctx, options.Logger, func() error {
+		*attempts++
+		return s.run(ctx, cmd, timeout, options)
+	})
+
+	if cerr := closeWriters(err != nil); cerr != nil && err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// writers returns the writers for the output of the step. In silent mode the output
+// is captured and only written if the step has failed. The output is also written to the file.
+func (s *Step) writers(file string, opts *RunOpts) (io.Writer, io.Writer, func(failed bool) error, error) {
+	stdout, stderr := opts.Stdout, opts.Stderr
+
+	var buf *output.Buffer
+	if opts.Silent && !opts.Dry {
+		buf = output.NewBuffer()
+		stdout, stderr = buf.Writer(stdout), buf.Writer(stderr)
+	}
+
+	var f *os.File
+	var mw *mask.Writer
+	if file != "" && !opts.Dry {
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(opts.WorkingDir.String(), path)
 		}
 
-		return nil
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, nil, nil, err
+		}
+
+		var err error
+		f, err = os.Create(path)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		var w io.Writer = f
+		if opts.Masker != nil {
+			mw = opts.Masker.Writer(f)
+			w = mw
+		}
+
+		stdout, stderr = io.MultiWriter(stdout, w), io.MultiWriter(stderr, w)
 	}
 
-	for _, cmd := range cmds {
+	return stdout, stderr, func(failed bool) error {
+		if mw != nil {
+			if err := mw.Flush(); err != nil {
+				return err
+			}
+		}
 
-		err := s.runCmd(ctx, cmd, timeout, options)
-		if err != nil && !s.ContinueOnError {
-			return err
+		if f != nil {
+			if err := f.Close(); err != nil {
+				return err
+			}
 		}
+
+		if buf != nil && failed {
+			return buf.Replay()
+		}
+
+		return nil
+	}, nil
+}
+
+func (s *Step) run(ctx context.Context, cmd string, timeout time.Duration, opts *RunOpts) error {
+	if opts.Dry {
+		if s.Uses != "" {
+			fmt.Fprintf(opts.Stdout, "uses: %s\n", s.Uses)
+			return nil
+		}
+
+		names := maps.Keys(opts.Env)
+		sort.Strings(names)
+
+		fmt.Fprintf(opts.Stdout, "# env: %s\n", strings.Join(names, " "))
+		fmt.Fprintln(opts.Stdout, strings.TrimRight(cmd, "\n"))
+
+		return nil
 	}
 
-	return nil
+	if s.Uses != "" {
+		return s.runRemote(ctx, s.Uses, timeout, opts)
+	}
+
+	shell := opts.Shell
+	if s.Shell != "" {
+		shell = s.Shell
+	}
+
+	if !shell.Builtin() {
+		return s.runShell(ctx, shell, cmd, timeout, opts)
+	}
+
+	return s.runCmd(ctx, cmd, timeout, opts)
+}
+
+// errexit returns true if the script stops at the first failing command,
+// which is the default.
+func (s *Step) errexit() bool {
+	return s.ErrExit == nil || *s.ErrExit
 }
 
-func (s *Step) runRemote(ctx context.Context, path string, timeout time.Duration) error {
-	m := &plugin.Meta{Path: path}
+func (s *Step) runRemote(ctx context.Context, path string, timeout time.Duration, opts *RunOpts) error {
+	m := &plugin.Meta{
+		Path:   path,
+		Logger: opts.Logger,
+		Stdout: opts.Stdout,
+		Stderr: opts.Stderr,
+	}
 	f := m.Factory(ctx)
 
 	p, err := f()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	defer p.Close()
 
-	_, err = p.Execute(plugin.ExecuteRequest{})
+	_, err = p.Execute(plugin.ExecuteRequest{Vars: s.With})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	return nil
@@ -366,10 +966,11 @@ func (s *Step) runCmd(ctx context.Context, cmd string, timeout time.Duration, op
 	}
 
 	r, err := interp.New(
+		interp.Params(append([]string{"--"}, opts.Args...)...),
 		interp.Dir(string(opts.WorkingDir)),
-		interp.Env(expand.ListEnviron(append(os.Environ(), utils.Strings(opts.Env)...)...)),
+		interp.Env(expand.ListEnviron(utils.Strings(opts.Env)...)),
 
-		interp.Module(interp.DefaultExec),
+		interp.Module(execModule(opts)),
 		interp.Module(interp.OpenDevImpls(interp.DefaultOpen)),
 
 		interp.StdIO(opts.Stdin, opts.Stdout, opts.Stderr),
@@ -378,7 +979,11 @@ func (s *Step) runCmd(ctx context.Context, cmd string, timeout time.Duration, op
 		return err
 	}
 
-	err = r.Run(ctx, p)
+	err = runScript(ctx, r, p, s.errexit())
+	if code, ok := ExitCode(err); ok && code == 0 {
+		return nil
+	}
+
 	if err != nil {
 		return err
 	}
@@ -445,6 +1050,88 @@ func (v Vars) Merge(vars Vars) {
 // Env ...
 type Env map[string]string
 
+// Merge ...
+func (e Env) Merge(env Env) {
+	maps.Copy(e, env)
+}
+
+// EnvFiles ...
+type EnvFiles []EnvFile
+
+// UnmarshalYAML ...
+func (e *EnvFiles) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var f EnvFile
+		if err := value.Decode(&f); err != nil {
+			return err
+		}
+		*e = EnvFiles{f}
+
+		return nil
+	}
+
+	var ff []EnvFile
+	if err := value.Decode(&ff); err != nil {
+		return err
+	}
+	*e = ff
+
+	return nil
+}
+
+// Load reads the files in order and merges them into the environment.
+// Relative paths are resolved against the working directory. Variables in the
+// files are expanded from the environment, or else from the inherited environ.
+func (e EnvFiles) Load(dir WorkingDir, env Env, environ Env) error {
+	lookup := func(key string) (string, bool) {
+		if v, ok := env[key]; ok {
+			return v, true
+		}
+
+		v, ok := environ[key]
+
+		return v, ok
+	}
+
+	for _, f := range e {
+		path := f.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir.String(), path)
+		}
+
+		vars, err := dotenv.Read(path, lookup)
+		if errors.Is(err, fs.ErrNotExist) && f.Optional {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		env.Merge(vars)
+	}
+
+	return nil
+}
+
+// EnvFile ...
+type EnvFile struct {
+	Path     string `yaml:"path"`
+	Optional bool   `yaml:"optional"`
+}
+
+// UnmarshalYAML ...
+func (e *EnvFile) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		e.Path = value.Value
+		return nil
+	}
+
+	type envFile EnvFile
+
+	return value.Decode((*envFile)(e))
+}
+
 // DependsOn ...
 type DependsOn []string
 
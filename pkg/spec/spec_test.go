@@ -1,9 +1,19 @@
 package spec
 
 import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/katallaxie/run/pkg/tmpl"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
 func TestVars_Merge(t *testing.T) {
@@ -16,3 +26,451 @@ func TestVars_Merge(t *testing.T) {
 
 	assert.Equal(t, Vars{"foo": "bar2"}, m)
 }
+
+func TestSpec_Graph(t *testing.T) {
+	s := &Spec{
+		Tasks: Tasks{
+			"build": Task{DependsOn: DependsOn{"test", "lint"}},
+			"test":  Task{DependsOn: DependsOn{"lint"}},
+			"lint":  Task{},
+		},
+	}
+
+	g, err := s.Graph("build")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lint", "test", "build"}, g.Nodes)
+	assert.Equal(t, []Edge{{From: "test", To: "lint"}, {From: "build", To: "test"}, {From: "build", To: "lint"}}, g.Edges)
+
+	s.Tasks["lint"] = Task{DependsOn: DependsOn{"build"}}
+
+	_, err = s.Graph("build")
+	assert.ErrorIs(t, err, ErrCyclicDependency)
+
+	_, err = s.Graph("missing")
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+}
+
+func TestGraph_Write(t *testing.T) {
+	g := &Graph{
+		Nodes: []string{`say "hi"`, "build"},
+		Edges: []Edge{{From: "build", To: `say "hi"`}},
+	}
+
+	var out bytes.Buffer
+	err := g.WriteDot(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, "digraph run {\n  \"say \\\"hi\\\"\";\n  \"build\";\n  \"build\" -> \"say \\\"hi\\\"\";\n}\n", out.String())
+
+	out.Reset()
+	err = g.WriteMermaid(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, "graph TD\n  t0[\"say #quot;hi#quot;\"]\n  t1[\"build\"]\n  t1 --> t0\n", out.String())
+}
+
+func TestEnvFiles_Load(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, ".env"), []byte("export FOO=bar\nBAZ=${FOO}-$QUX"), 0600)
+	assert.NoError(t, err)
+
+	var s struct {
+		EnvFile EnvFiles `yaml:"env-file"`
+	}
+
+	err = yaml.Unmarshal([]byte("env-file: [.env, {path: .env.local, optional: true}]"), &s)
+	assert.NoError(t, err)
+	assert.Equal(t, EnvFiles{{Path: ".env"}, {Path: ".env.local", Optional: true}}, s.EnvFile)
+
+	env := Env{"QUX": "qux"}
+	err = s.EnvFile.Load(WorkingDir(dir), env, Env{"QUX": "environ"})
+	assert.NoError(t, err)
+	assert.Equal(t, Env{"FOO": "bar", "BAZ": "bar-qux", "QUX": "qux"}, env)
+
+	env = make(Env)
+	err = s.EnvFile.Load(WorkingDir(dir), env, Env{"QUX": "environ"})
+	assert.NoError(t, err)
+	assert.Equal(t, Env{"FOO": "bar", "BAZ": "bar-environ"}, env)
+
+	// the environment of the process is not inherited
+	t.Setenv("QUX", "process")
+	env = make(Env)
+	err = s.EnvFile.Load(WorkingDir(dir), env, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Env{"FOO": "bar", "BAZ": "bar-"}, env)
+
+	err = EnvFiles{{Path: ".env.local"}}.Load(WorkingDir(dir), env, nil)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestStep_Run_Silent(t *testing.T) {
+	dir := t.TempDir()
+
+	var out bytes.Buffer
+	opts := []RunOpt{WithSilent(true), WithStdout(&out), WithStderr(&out), WithWorkingDir(WorkingDir(dir))}
+
+	s := &Step{Cmd: "echo {{.name}}", Output: "logs/{{.name}}.log", Vars: Vars{"name": "quiet"}}
+	err := s.Run(context.Background(), opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, "", out.String())
+
+	b, err := os.ReadFile(filepath.Join(dir, "logs", "quiet.log"))
+	assert.NoError(t, err)
+	assert.Equal(t, "quiet\n", string(b))
+
+	s = &Step{Cmd: "echo loud; exit 1"}
+	err = s.Run(context.Background(), opts...)
+	assert.Error(t, err)
+	assert.Equal(t, "loud\n", out.String())
+}
+
+func TestStep_Run_Template(t *testing.T) {
+	var out bytes.Buffer
+	opts := []RunOpt{WithStdout(&out), WithStderr(&out), WithWorkingDir(WorkingDir(t.TempDir()))}
+
+	// the format of go list, docker or kubectl is not a var of the step
+	s := &Step{Cmd: `echo '{{.ImportPath}} {{ .State.Status }}' {{.name}}`, Vars: Vars{"name": "fmt"}}
+	err := s.Run(context.Background(), opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, "{{.ImportPath}} {{ .State.Status }} fmt\n", out.String())
+
+	out.Reset()
+	s = &Step{Cmd: `echo '{{range .Items}}{{.Name}}{{end}}' {{json .}}`}
+	err = s.Run(context.Background(), opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, "{{range .Items}}{{.Name}}{{end}} {{json .}}\n", out.String())
+
+	out.Reset()
+	s = &Step{Cmd: `echo {{if .DEBUG}}-v{{end}} {{.name}}`, Vars: Vars{"name": "x"}}
+	err = s.Run(context.Background(), opts...)
+	assert.ErrorIs(t, err, tmpl.ErrUnknownControl)
+	assert.Empty(t, out.String())
+}
+
+func TestStep_Run_Retry(t *testing.T) {
+	dir := t.TempDir()
+
+	var out bytes.Buffer
+	opts := []RunOpt{WithStdout(&out), WithStderr(&out), WithWorkingDir(WorkingDir(dir))}
+
+	// succeeds with the third attempt
+	s := &Step{
+		Cmd:   `[ -f b ] && exit 0; [ -f a ] && : > b; : > a; exit 1`,
+		Retry: &Retry{Attempts: 3, Delay: time.Millisecond, Backoff: 2},
+	}
+	err := s.Run(context.Background(), opts...)
+	assert.NoError(t, err)
+
+	s = &Step{Cmd: "exit 3", Retry: &Retry{Attempts: 2}}
+	err = s.Run(context.Background(), opts...)
+
+	var retryErr *RetryError
+	assert.ErrorAs(t, err, &retryErr)
+	assert.Len(t, retryErr.Errors, 2)
+
+	s = &Step{Cmd: "exit 3", Retry: &Retry{Attempts: 2, OnExitCodes: []int{4}}}
+	err = s.Run(context.Background(), opts...)
+	assert.EqualError(t, err, "exit status 3")
+}
+
+func TestShell_Args(t *testing.T) {
+	type test struct {
+		shell    Shell
+		expected []string
+		hasError bool
+	}
+
+	tests := []test{
+		{shell: ShellBash, expected: []string{"bash", "--noprofile", "--norc", "-eo", "pipefail", "script"}},
+		{shell: ShellSh, expected: []string{"sh", "-e", "script"}},
+		{shell: "node {0}", expected: []string{"node", "script"}},
+		{shell: "pwsh -File", expected: []string{"pwsh", "-File", "script"}},
+		{shell: "{0}", hasError: true},
+	}
+
+	for _, tc := range tests {
+		args, err := tc.shell.Args("script")
+		if tc.hasError {
+			assert.Error(t, err)
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, args)
+	}
+}
+
+func TestStep_Run_Script(t *testing.T) {
+	dir := t.TempDir()
+
+	var out bytes.Buffer
+	opts := []RunOpt{WithStdout(&out), WithStderr(&out), WithWorkingDir(WorkingDir(dir))}
+
+	err := os.Mkdir(filepath.Join(dir, "sub"), 0o755)
+	assert.NoError(t, err)
+
+	s := &Step{
+		Cmd: `cd sub
+NAME=world
+if [ -n "$NAME" ]; then
+  echo "hello \
+$NAME"
+fi
+read -r dir <<EOT
+${PWD##*/}
+EOT
+false && echo unreachable
+echo "$dir"
+`,
+	}
+	err = s.Run(context.Background(), opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world\nsub\n", out.String())
+
+	out.Reset()
+	s = &Step{Cmd: "false\necho unreachable"}
+	err = s.Run(context.Background(), opts...)
+	assert.EqualError(t, err, "exit status 1")
+	assert.Empty(t, out.String())
+
+	errexit := false
+	s = &Step{Cmd: "false\necho reachable", ErrExit: &errexit}
+	err = s.Run(context.Background(), opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, "reachable\n", out.String())
+
+	out.Reset()
+	s = &Step{Cmd: "if true; then\n  false\n  echo unreachable\nfi\necho unreachable", ErrExit: &errexit}
+	err = s.Run(context.Background(), opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, "unreachable\nunreachable\n", out.String())
+
+	out.Reset()
+	s = &Step{Cmd: "set -e\nfalse\necho unreachable", ErrExit: &errexit}
+	err = s.Run(context.Background(), opts...)
+	assert.EqualError(t, err, "exit status 1")
+	assert.Empty(t, out.String())
+}
+
+func TestStep_Run_ErrExit(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  string
+		out  string
+		code int
+	}{
+		{name: "if", cmd: "if true; then\n  false\n  echo unreachable\nfi\necho unreachable", code: 1},
+		{name: "else", cmd: "if false; then :; elif false; then :; else\n  (exit 3)\n  echo unreachable\nfi", code: 3},
+		{name: "if condition", cmd: "if false; then :; elif ! true; then :; fi\necho reachable", out: "reachable\n"},
+		{name: "for", cmd: "for i in 1 2; do\n  echo $i\n  [ $i -lt 1 ]\ndone\necho unreachable", out: "1\n", code: 1},
+		{name: "while", cmd: "i=0\nwhile [ $i -lt 2 ]; do\n  i=$((i+1))\n  echo $i\n  false\ndone", out: "1\n", code: 1},
+		{name: "until condition", cmd: "i=0\nuntil [ $i -ge 2 ]; do i=$((i+1)); done\necho $i", out: "2\n"},
+		{name: "list", cmd: "{\n  false && echo unreachable\n  false || echo reachable\n  true && false\n  echo unreachable\n}", out: "reachable\n", code: 1},
+		{name: "case", cmd: "case a in\n  a) false; echo unreachable;;\nesac", code: 1},
+		{name: "func", cmd: "f() {\n  false\n  echo unreachable\n}\nf\necho unreachable", code: 1},
+		{name: "subshell", cmd: "(\n  false\n  echo unreachable\n)\necho unreachable", code: 1},
+		{name: "negated", cmd: "while true; do\n  ! true\n  echo reachable\n  break\ndone", out: "reachable\n"},
+		{name: "set +e", cmd: "set +e\nfalse\necho $?\nset -e\nfalse\necho unreachable", out: "1\n", code: 1},
+		{name: "set +o errexit", cmd: "set -u +o errexit\nfalse\necho reachable\necho $UNSET\necho unreachable", out: "reachable\nUNSET: unbound variable\n", code: 1},
+		{name: "set in subshell", cmd: "(set +e; false)\necho unreachable", code: 1},
+		{name: "func in condition", cmd: "f() {\n  false\n  echo reachable\n}\nif f; then echo reachable; fi\nf && ! f\nf\necho unreachable", out: "reachable\nreachable\nreachable\nreachable\n", code: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			s := &Step{Cmd: tc.cmd}
+			err := s.Run(context.Background(), WithStdout(&out), WithStderr(&out), WithWorkingDir(WorkingDir(t.TempDir())))
+
+			code, _ := ExitCode(err)
+			assert.Equal(t, tc.code, code)
+			assert.Equal(t, tc.out, out.String())
+		})
+	}
+}
+
+func TestMatrix_Combinations(t *testing.T) {
+	var task Task
+	err := yaml.Unmarshal([]byte(`
+matrix:
+  goos: [linux, darwin]
+  goarch: [amd64, arm64]
+  exclude:
+    - goos: darwin
+      goarch: amd64
+  include:
+    - goos: linux
+      cgo: "1"
+    - goos: windows
+      goarch: amd64
+`), &task)
+	assert.NoError(t, err)
+
+	names := make([]string, 0)
+	for _, c := range task.Matrix.Combinations() {
+		names = append(names, task.Matrix.Name("build", c))
+	}
+
+	assert.Equal(t, []string{
+		"build[goos=linux,goarch=amd64,cgo=1]",
+		"build[goos=linux,goarch=arm64,cgo=1]",
+		"build[goos=darwin,goarch=arm64]",
+		"build[goos=windows,goarch=amd64]",
+	}, names)
+	assert.Equal(t, Env{"GOOS": "linux"}, task.Matrix.Env(Vars{"goos": "linux"}))
+}
+
+func TestEnvInherit_UnmarshalYAML(t *testing.T) {
+	type test struct {
+		input    string
+		expected EnvInherit
+		hasError bool
+	}
+
+	tests := []test{
+		{input: "all", expected: EnvInherit{All: true}},
+		{input: "none", expected: EnvInherit{}},
+		{input: "[CI, GOFLAGS]", expected: EnvInherit{Names: []string{"CI", "GOFLAGS"}}},
+		{input: "some", hasError: true},
+	}
+
+	for _, tc := range tests {
+		var e EnvInherit
+		err := yaml.Unmarshal([]byte(tc.input), &e)
+		if tc.hasError {
+			assert.Error(t, err)
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, e)
+	}
+}
+
+func TestTask_Run_Finally(t *testing.T) {
+	var out bytes.Buffer
+	opts := []RunOpt{WithStdout(&out), WithStderr(&out)}
+
+	task := &Task{
+		Steps: Steps{
+			{Cmd: `echo "deferred {{.TASK_OUTCOME}} {{.TASK_FAILED_STEP}}"`, Defer: true},
+			{Cmd: "exit 3", Id: "fail"},
+			{Cmd: "echo unreachable"},
+		},
+		Finally: Steps{
+			{Cmd: "exit 4"},
+			{Cmd: `echo "finally {{.TASK_ERROR}}"`},
+		},
+	}
+
+	err := task.Run(context.Background(), opts...)
+	assert.EqualError(t, err, "exit status 3")
+	assert.Equal(t, "deferred failed fail\nfinally exit status 3\n", out.String())
+
+	var stepErr *StepError
+	assert.ErrorAs(t, err, &stepErr)
+	assert.Equal(t, "fail", stepErr.Step)
+
+	out.Reset()
+	task = &Task{
+		Steps:   Steps{{Cmd: "echo ok"}},
+		Finally: Steps{{Cmd: "exit 4"}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = task.Run(ctx, opts...)
+	assert.EqualError(t, err, "context canceled")
+
+	task.Steps = nil
+	err = task.Run(context.Background(), opts...)
+	assert.EqualError(t, err, "exit status 4")
+}
+
+func TestTask_Run_Requires(t *testing.T) {
+	dir := t.TempDir()
+
+	var out bytes.Buffer
+	opts := []RunOpt{WithStdout(&out), WithStderr(&out), WithWorkingDir(WorkingDir(dir)), WithExtraEnv(Env{"SET": "1", "PATH": dir})}
+
+	task := &Task{
+		Requires: &Requires{
+			Bins:  []string{"missing-bin"},
+			Env:   []string{"SET", "UNSET"},
+			Files: []string{"missing.txt"},
+			Preconditions: []Precondition{
+				{Cmd: "exit 0"},
+				{Cmd: "exit 1", Msg: "custom message"},
+			},
+		},
+		Steps: Steps{{Cmd: "echo unreachable"}},
+	}
+
+	err := task.Run(context.Background(), opts...)
+
+	var reqErr *RequiresError
+	assert.ErrorAs(t, err, &reqErr)
+	assert.Equal(t, []string{
+		"executable missing-bin is not in the PATH, please install it",
+		"environment variable UNSET is not set",
+		"file missing.txt does not exist",
+		"custom message",
+	}, reqErr.Unmet)
+	assert.Empty(t, out.String())
+}
+
+func TestTask_Shell_Checks(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not installed")
+	}
+
+	var out bytes.Buffer
+	opts := []RunOpt{WithStdout(&out), WithStderr(&out), WithWorkingDir(WorkingDir(t.TempDir())), WithExtraEnv(Env{"PATH": os.Getenv("PATH")})}
+
+	// BASH_VERSION is only set by bash, not by the builtin shell
+	task := &Task{
+		Shell:    ShellBash,
+		Status:   []string{`[[ -n $BASH_VERSION ]]`},
+		Requires: &Requires{Preconditions: []Precondition{{Cmd: `[[ -n $BASH_VERSION ]]`, Msg: "no bash"}}},
+		Steps:    Steps{{Cmd: "echo ran"}},
+	}
+
+	ok, err := task.UpToDate(context.Background(), opts...)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	err = task.Run(context.Background(), opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, "ran\n", out.String())
+
+	task.Shell = ""
+	ok, err = task.UpToDate(context.Background(), append(opts, WithShell(ShellBash))...)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = task.UpToDate(context.Background(), opts...)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	err = task.Run(context.Background(), opts...)
+	assert.EqualError(t, err, "unmet requirements:\n  - no bash")
+}
+
+func TestSecrets_Resolve(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("RUN_TEST_TOKEN", "t0ken")
+
+	s := Secrets{
+		"TOKEN": {Env: "RUN_TEST_TOKEN"},
+		"CMD":   {Cmd: ": > ran; echo s3cr3t"},
+	}
+
+	env, err := s.Resolve(context.Background(), WithWorkingDir(WorkingDir(dir)), WithDry(true))
+	assert.NoError(t, err)
+	assert.Equal(t, Env{"TOKEN": "t0ken", "CMD": DrySecret}, env)
+	assert.NoFileExists(t, filepath.Join(dir, "ran"))
+
+	env, err = s.Resolve(context.Background(), WithWorkingDir(WorkingDir(dir)))
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cr3t", env["CMD"])
+	assert.FileExists(t, filepath.Join(dir, "ran"))
+}
@@ -11,6 +11,7 @@ type Opts struct {
 	Funcs                 template.FuncMap
 	FailOnMissing         bool
 	DisableReplaceNoValue bool
+	KeepUnknown           bool
 }
 
 // TmplFields ...
@@ -62,3 +63,11 @@ func WithFailOnMissing() Opt {
 		opts.FailOnMissing = true
 	}
 }
+
+// WithKeepUnknown keeps the actions that use fields which are not set as they are,
+// e.g. the `{{.ImportPath}}` of `go list -f`, and fails on missing keys otherwise.
+func WithKeepUnknown() Opt {
+	return func(opts *Opts) {
+		opts.KeepUnknown = true
+	}
+}
@@ -2,11 +2,18 @@ package tmpl
 
 import (
 	"bytes"
+	"errors"
 	"io/ioutil"
 	"os"
+	"strings"
 	"text/template"
+	"text/template/parse"
 )
 
+// ErrUnknownControl signals an if, range or with of a field that is not set
+// in a template with actions that are rendered.
+var ErrUnknownControl = errors.New("if, range or with of an unknown field cannot be kept with the other actions")
+
 // Template ...
 type Template struct {
 	opts Opts
@@ -54,10 +61,25 @@ func (t *Template) ApplyFile(in, out string) error {
 // Apply ...
 func (t *Template) Apply(s string) (string, error) {
 	tmpl, err := template.New("").Funcs(t.opts.Funcs).Parse(s)
+	if err != nil && t.opts.KeepUnknown {
+		return s, nil
+	}
+
 	if err != nil {
 		return "", err
 	}
 
+	if t.opts.KeepUnknown {
+		if !t.keep(s, tmpl.Tree.Root) {
+			if t.known(tmpl.Tree.Root) {
+				return "", ErrUnknownControl
+			}
+
+			return s, nil
+		}
+		tmpl.Option("missingkey=error")
+	}
+
 	if t.opts.FailOnMissing {
 		tmpl.Option("missingkey=error")
 	}
@@ -75,3 +97,106 @@ func (t *Template) Apply(s string) (string, error) {
 
 	return string(b), err
 }
+
+// keep replaces the actions that use unknown fields with their source. It reports false
+// if an if, range or with uses unknown fields, which are only kept with the whole template.
+func (t *Template) keep(src string, list *parse.ListNode) bool {
+	if list == nil {
+		return true
+	}
+
+	for i, n := range list.Nodes {
+		switch n := n.(type) {
+		case *parse.ActionNode:
+			if !t.unknown(n.Pipe) {
+				continue
+			}
+
+			start := strings.LastIndex(src[:n.Pos], "{{")
+			end := strings.Index(src[n.Pos:], "}}")
+			if start < 0 || end < 0 {
+				return false
+			}
+			list.Nodes[i] = &parse.TextNode{NodeType: parse.NodeText, Pos: n.Pos, Text: []byte(src[start : int(n.Pos)+end+2])}
+		case *parse.IfNode:
+			if t.unknown(n.Pipe) || !t.keep(src, n.List) || !t.keep(src, n.ElseList) {
+				return false
+			}
+		case *parse.RangeNode:
+			if t.unknown(n.Pipe) {
+				return false
+			}
+		case *parse.WithNode:
+			if t.unknown(n.Pipe) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// known reports if the list has actions that use no unknown fields.
+func (t *Template) known(list *parse.ListNode) bool {
+	if list == nil {
+		return false
+	}
+
+	for _, n := range list.Nodes {
+		switch n := n.(type) {
+		case *parse.ActionNode:
+			if !t.unknown(n.Pipe) {
+				return true
+			}
+		case *parse.IfNode:
+			if !t.unknown(n.Pipe) || t.known(n.List) || t.known(n.ElseList) {
+				return true
+			}
+		case *parse.RangeNode:
+			if !t.unknown(n.Pipe) || t.known(n.List) || t.known(n.ElseList) {
+				return true
+			}
+		case *parse.WithNode:
+			if !t.unknown(n.Pipe) || t.known(n.List) || t.known(n.ElseList) {
+				return true
+			}
+		case *parse.TemplateNode:
+			return true
+		}
+	}
+
+	return false
+}
+
+// unknown reports if the node uses a field that is not set.
+func (t *Template) unknown(n parse.Node) bool {
+	switch n := n.(type) {
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, c := range n.Cmds {
+			if t.unknown(c) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if t.unknown(arg) {
+				return true
+			}
+		}
+	case *parse.ChainNode:
+		return t.unknown(n.Node)
+	case *parse.FieldNode:
+		_, ok := t.opts.Fields[n.Ident[0]]
+		return !ok
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			_, ok := t.opts.Fields[n.Ident[1]]
+			return !ok
+		}
+	}
+
+	return false
+}
@@ -30,6 +30,13 @@ func TestTemplate_Apply(t *testing.T) {
 		{input: "{{.FOO}}", want: "", err: nil},
 		{input: "{{.FOO}}", want: "<no value>", err: nil, opts: []tmpl.Opt{tmpl.WithDisableReplaceNoValue()}},
 		{input: "{{.FOO}}", want: "", hasError: true, err: errors.New("map has no entry for key"), opts: []tmpl.Opt{tmpl.WithFailOnMissing()}},
+		{input: "{{.FOO}} {{ .BAR }} {{OS}}", want: "{{.FOO}} {{ .BAR }} " + runtime.GOOS, opts: []tmpl.Opt{tmpl.WithKeepUnknown()}},
+		{input: "{{.FOO}} {{.NAME | upper}} {{$.NAME}}", want: "{{.FOO}} RUN run", opts: []tmpl.Opt{tmpl.WithKeepUnknown(), tmpl.WithExtraFields(tmpl.TmplFields{"NAME": "run"})}},
+		{input: "{{if .FOO}}a{{end}} {{.NAME}}", want: "", hasError: true, err: tmpl.ErrUnknownControl, opts: []tmpl.Opt{tmpl.WithKeepUnknown(), tmpl.WithExtraFields(tmpl.TmplFields{"NAME": "run"})}},
+		{input: "{{if .FOO}}{{.NAME}}{{end}}", want: "", hasError: true, err: tmpl.ErrUnknownControl, opts: []tmpl.Opt{tmpl.WithKeepUnknown(), tmpl.WithExtraFields(tmpl.TmplFields{"NAME": "run"})}},
+		{input: "{{if .FOO}}{{.BAR}}{{else}}b{{end}} {{.BAZ}}", want: "{{if .FOO}}{{.BAR}}{{else}}b{{end}} {{.BAZ}}", opts: []tmpl.Opt{tmpl.WithKeepUnknown(), tmpl.WithExtraFields(tmpl.TmplFields{"NAME": "run"})}},
+		{input: "{{json .}}", want: "{{json .}}", opts: []tmpl.Opt{tmpl.WithKeepUnknown()}},
+		{input: "{{.NAME.FOO}}", want: "", hasError: true, err: errors.New("can't evaluate field FOO"), opts: []tmpl.Opt{tmpl.WithKeepUnknown(), tmpl.WithExtraFields(tmpl.TmplFields{"NAME": "run"})}},
 	}
 
 	for _, tc := range tests {
@@ -1,13 +1,73 @@
 package utils
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
 
 // Strings ...
 func Strings(m map[string]string) []string {
-	ss := make([]string, len(m))
+	ss := make([]string, 0, len(m))
 	for k, v := range m {
 		ss = append(ss, fmt.Sprintf("%s=%s", k, v))
 	}
+	sort.Strings(ss)
 
 	return ss
 }
+
+// Map parses a list of key=value pairs into a map.
+func Map(ss []string) (map[string]string, error) {
+	m := make(map[string]string, len(ss))
+	for _, s := range ss {
+		k, v, ok := strings.Cut(s, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid key=value pair: %s", s)
+		}
+		m[k] = v
+	}
+
+	return m, nil
+}
+
+// Environ returns the environment of the process as a map.
+func Environ() map[string]string {
+	m := make(map[string]string)
+	for _, s := range os.Environ() {
+		k, v, ok := strings.Cut(s, "=")
+		if !ok || k == "" {
+			continue
+		}
+		m[k] = v
+	}
+
+	return m
+}
+
+// Quote joins the arguments to a string that a shell splits into the same
+// arguments. Arguments with special characters are single quoted.
+func Quote(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, quote(arg))
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+func quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+
+	safe := strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("@%+=:,./_-", r))
+	}) < 0
+	if safe {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
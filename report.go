@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/katallaxie/run/pkg/config"
+	"github.com/katallaxie/run/pkg/mask"
+	"github.com/katallaxie/run/pkg/report"
+)
+
+// writeReports writes the summary of the tasks to stderr and the report files of the flags.
+// The secrets are masked in the files.
+func writeReports(cfg *config.Config, rep *report.Report, masker *mask.Masker) error {
+	if len(rep.Tasks) > 0 && !cfg.Flags.Dry {
+		if _, err := io.WriteString(cfg.Stderr, "\n"); err != nil {
+			return err
+		}
+
+		if err := rep.WriteTable(cfg.Stderr); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Flags.ReportJUnit != "" {
+		if err := writeReport(cfg.Flags.ReportJUnit, masker, rep.WriteJUnit); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Flags.ReportJSON != "" {
+		if err := writeReport(cfg.Flags.ReportJSON, masker, rep.WriteJSON); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeReport(path string, masker *mask.Masker, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := masker.Writer(f)
+	if err := write(w); err != nil {
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
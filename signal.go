@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/katallaxie/run/pkg/config"
+
+	"go.uber.org/zap"
+)
+
+// trap cancels the context on an interrupt or the term signal. A second signal
+// closes the returned kill channel, so that the running steps are killed without
+// waiting for the grace period. The returned func reports the first received signal, if any.
+func trap(cancel context.CancelFunc, cfg *config.Config, logger *zap.Logger) (func() os.Signal, <-chan struct{}) {
+	var mu sync.Mutex
+	var received os.Signal
+
+	kill := make(chan struct{})
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, cfg.TermSignal)
+
+	go func() {
+		killed := false
+
+		// the signals are trapped until run exits, so that the steps are not orphaned
+		for sig := range sigs {
+			mu.Lock()
+			first := received == nil
+			if first {
+				received = sig
+			}
+			mu.Unlock()
+
+			switch {
+			case first:
+				logger.Warn("shutting down", zap.String("signal", sig.String()), zap.Duration("grace-period", cfg.GracePeriod))
+				cancel()
+			case !killed:
+				logger.Warn("killing the running steps", zap.String("signal", sig.String()))
+				close(kill)
+				killed = true
+			}
+		}
+	}()
+
+	return func() os.Signal {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return received
+	}, kill
+}
+
+// exitCode returns the conventional exit code for a signal, e.g. 130 for an interrupt.
+func exitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+
+	return 1
+}